@@ -0,0 +1,68 @@
+package blobber
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// containerAccessLevel reads the declared public access level of a found container with
+// Get Container ACL. ADLS Gen2 filesystems are queried through the blob endpoint.
+func containerAccessLevel(account, container, domain string) string {
+	if adls {
+		domain = strings.Replace(domain, "dfs.", "blob.", 1)
+	}
+
+	sleepJitter()
+	resp, err := httpGet(azure.ACLURL(account, domain, container))
+	if err != nil {
+		if debug {
+			red := color.New(color.FgRed)
+			BarPrintf(mainProgressBar, red, "[DEBUG] %s: ACL request failed: %v", containerLabel(account, container, domain), err)
+		}
+		return azure.AccessLevelUnknown
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return azure.AccessLevelUnknown
+	}
+
+	level, identifiers := azure.ParseACL(resp, body)
+	if debug && resp.StatusCode != http.StatusOK {
+		yellow := color.New(color.FgYellow)
+		BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: ACL not readable, HTTP %d", containerLabel(account, container, domain), resp.StatusCode)
+	}
+	if len(identifiers) > 0 {
+		cyan := color.New(color.FgCyan)
+		BarPrintf(mainProgressBar, cyan, "[INFO] %s: Stored access policies (%d): %s", containerLabel(account, container, domain), len(identifiers), policySummary(identifiers))
+	}
+	return level
+}
+
+// policySummary lists stored access policies as "id (permissions, expires ...)"
+func policySummary(identifiers []azure.SignedIdentifier) string {
+	parts := make([]string, 0, len(identifiers))
+	for _, id := range identifiers {
+		part := id.ID
+		if id.AccessPolicy.Permission != "" || id.AccessPolicy.Expiry != "" {
+			part += fmt.Sprintf(" (%s, expires %s)", id.AccessPolicy.Permission, id.AccessPolicy.Expiry)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// accessLevelSuffix formats the access level for a FOUND message, empty unless --acl is set
+func accessLevelSuffix(level string) string {
+	if level == "" {
+		return ""
+	}
+	return fmt.Sprintf(" [access level: %s]", level)
+}
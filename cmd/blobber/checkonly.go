@@ -0,0 +1,71 @@
+package blobber
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// scanners holds one library scanner per base domain for --check-only mode
+var scanners map[string]*azure.Scanner
+
+// newScanners creates a scanner per base domain sharing the global HTTP client. Requests
+// honour --jitter and --request-timeout, and debug messages print above the progress bar.
+func newScanners(domains []string) map[string]*azure.Scanner {
+	result := make(map[string]*azure.Scanner, len(domains))
+	for _, domain := range domains {
+		scanner := azure.NewScannerWithClient(azure.Config{
+			BaseDomain: domain,
+			ADLS:       adls,
+			Debug:      debug,
+		}, client)
+		scanner.SetLogger(func(format string, a ...interface{}) {
+			printAbove(mainProgressBar, fmt.Sprintf(format, a...))
+		})
+		scanner.SetRequestFunc(func(ctx context.Context, url string) (*http.Response, error) {
+			sleepJitter()
+			return httpGetContext(ctx, url)
+		})
+		result[domain] = scanner
+	}
+	return result
+}
+
+// checkAccessOnly reports whether a container is publicly accessible without enumerating its blobs
+func checkAccessOnly(account, container, domain string) azure.AccessResult {
+	target := containerLabel(account, container, domain)
+	result := scanners[domain].CheckAccess(account, container)
+
+	switch {
+	case result.IsPublic:
+		if aclCheck {
+			result.AccessLevel = containerAccessLevel(account, container, domain)
+		}
+		green := color.New(color.FgGreen)
+		BarPrintf(mainProgressBar, green, "[FOUND] %s is publicly accessible%s", target, accessLevelSuffix(result.AccessLevel))
+
+		if probeWellKnown {
+			result.SensitiveFiles = probeSensitiveFiles(account, container, domain)
+			writeSensitiveFiles(account, container, domain, result.SensitiveFiles)
+		}
+		if hook != nil {
+			hook.Notify(result, domain)
+		}
+	case result.ErrorCode == "PublicAccessNotPermitted":
+		yellow := color.New(color.FgYellow)
+		BarPrintf(mainProgressBar, yellow, "[INFO] %s: Public access not permitted", target)
+		if len(probeNames) > 0 {
+			result.Blobs = probeBlobs(account, container, domain, probeNames)
+			reportProbedBlobs(result, domain)
+		}
+	case debug:
+		yellow := color.New(color.FgYellow)
+		BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: %s", target, result.ErrorCode)
+	}
+
+	return result
+}
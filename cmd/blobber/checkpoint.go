@@ -0,0 +1,108 @@
+package blobber
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// checkpointFlushInterval is how often buffered completions are flushed to disk
+const checkpointFlushInterval = 5 * time.Second
+
+// checkpoint records completed account×container combinations so an interrupted scan can resume.
+// Each line holds "domain<TAB>account<TAB>container<TAB>outcome". Completions are written by a
+// single goroutine so workers never contend on the file.
+type checkpoint struct {
+	completed map[string]string
+	file      *os.File
+	records   chan string
+	done      chan struct{}
+}
+
+// openCheckpoint loads previously completed combinations from path and opens it for appending
+func openCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{
+		completed: make(map[string]string),
+		records:   make(chan string, 1024),
+		done:      make(chan struct{}),
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Split(scanner.Text(), "\t")
+			if len(fields) != 4 {
+				continue // Partially written line from a crash
+			}
+			c.completed[checkpointKey(fields[0], fields[1], fields[2])] = fields[3]
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	c.file = file
+
+	go c.writer()
+	return c, nil
+}
+
+// checkpointKey builds the lookup key for a combination
+func checkpointKey(domain, account, container string) string {
+	return domain + "\t" + account + "\t" + container
+}
+
+// Len returns the number of combinations completed by previous runs
+func (c *checkpoint) Len() int {
+	return len(c.completed)
+}
+
+// Completed reports whether a combination was completed by a previous run
+func (c *checkpoint) Completed(domain, account, container string) bool {
+	_, ok := c.completed[checkpointKey(domain, account, container)]
+	return ok
+}
+
+// Record queues a completed combination for writing
+func (c *checkpoint) Record(domain, account, container, outcome string) {
+	c.records <- checkpointKey(domain, account, container) + "\t" + outcome + "\n"
+}
+
+// Close flushes pending completions and closes the file
+func (c *checkpoint) Close() {
+	close(c.records)
+	<-c.done
+	c.file.Close()
+}
+
+// writer is the single goroutine that owns the checkpoint file
+func (c *checkpoint) writer() {
+	defer close(c.done)
+
+	w := bufio.NewWriter(c.file)
+	ticker := time.NewTicker(checkpointFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-c.records:
+			if !ok {
+				w.Flush()
+				return
+			}
+			w.WriteString(line)
+		case <-ticker.C:
+			w.Flush()
+		}
+	}
+}
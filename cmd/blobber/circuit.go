@@ -0,0 +1,98 @@
+package blobber
+
+import (
+	"slices"
+	"sort"
+	"sync"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// circuitThreshold is the number of consecutive failures after which a host is skipped, 0 disables it
+var circuitThreshold int
+
+// hostFailureCodes mean the host failed rather than the container, e.g. a reset connection or a 503
+var hostFailureCodes = []string{"RequestFailed", "ReadFailed", "ServerError", "ServerBusy", "InternalError", "OperationTimedOut"}
+
+// circuitOpenCode is the error code of containers skipped by an open circuit
+const circuitOpenCode = "CircuitOpen"
+
+// circuitBreaker counts consecutive failures per account host and opens the circuit
+// once a host reaches the threshold, so its remaining containers are skipped
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures map[string]int  // Consecutive failures per host
+	tripped  map[string]bool // Hosts with an open circuit
+	skipped  int             // Containers skipped because of open circuits
+}
+
+var breaker = &circuitBreaker{
+	failures: make(map[string]int),
+	tripped:  make(map[string]bool),
+}
+
+// circuitHost names the host a circuit is kept for
+func circuitHost(account, domain string) string {
+	return account + "." + domain
+}
+
+// isOpen reports whether the host's circuit is open and counts the skipped container if so
+func (c *circuitBreaker) isOpen(host string) bool {
+	if circuitThreshold <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tripped[host] {
+		c.skipped++
+		return true
+	}
+	return false
+}
+
+// record counts the outcome of a check against the host, opening its circuit on the
+// threshold-th consecutive failure
+func (c *circuitBreaker) record(host string, result azure.AccessResult) {
+	if circuitThreshold <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tripped[host] {
+		return
+	}
+	if !slices.Contains(hostFailureCodes, result.ErrorCode) {
+		delete(c.failures, host)
+		return
+	}
+
+	c.failures[host]++
+	if c.failures[host] >= circuitThreshold {
+		c.tripped[host] = true
+		yellow := color.New(color.FgYellow)
+		BarPrintf(mainProgressBar, yellow, "[WARN] %s: %d consecutive failures, skipping its remaining containers", host, c.failures[host])
+	}
+}
+
+// recordCircuitSkip counts a container skipped by an open circuit in the scan summary
+// under circuitOpenCode. It isn't checkpointed, so a resumed scan checks it again.
+func recordCircuitSkip(account, container, domain string) {
+	foundContainerLock.Lock()
+	summary.Add(azure.AccessResult{Account: account, Container: container, ErrorCode: circuitOpenCode})
+	foundContainerLock.Unlock()
+	recordError(account, container, domain, circuitOpenCode)
+}
+
+// trippedHosts returns the hosts with an open circuit in sorted order
+func (c *circuitBreaker) trippedHosts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hosts := make([]string, 0, len(c.tripped))
+	for host := range c.tripped {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
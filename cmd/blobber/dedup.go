@@ -0,0 +1,98 @@
+package blobber
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+)
+
+// dedupManifestName is the file, relative to the output directory, where duplicate mappings are recorded
+const dedupManifestName = "dedup-manifest.tsv"
+
+var (
+	dedupIndex    = make(map[string]string) // SHA-256 -> first downloaded path
+	dedupManifest *os.File
+	dedupLock     sync.Mutex
+)
+
+// dedupFile checks whether a file with the same SHA-256 was already downloaded and,
+// if so, replaces the new copy with a hardlink to the first one. Existing files skipped
+// by the download are passed too, so duplicates across runs are still found.
+// Cross-filesystem (or otherwise failing) links keep the duplicate in place.
+func dedupFile(bar *progressbar.ProgressBar, path string, sum []byte) {
+	key := hex.EncodeToString(sum)
+
+	dedupLock.Lock()
+	defer dedupLock.Unlock()
+
+	original, seen := dedupIndex[key]
+	if !seen {
+		dedupIndex[key] = path
+		return
+	}
+	if sameFile(original, path) {
+		return // Already linked, e.g. an existing file skipped on a re-run
+	}
+
+	// Link to a temporary name first so the duplicate is never lost if linking fails
+	tmp := path + ".dedup"
+	status := "linked"
+	if err := os.Link(original, tmp); err != nil {
+		status = "kept"
+		yellow := color.New(color.FgYellow)
+		BarPrintf(bar, yellow, "[INFO] Keeping duplicate %s (same as %s): %v", path, original, err)
+	} else if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		status = "kept"
+		yellow := color.New(color.FgYellow)
+		BarPrintf(bar, yellow, "[INFO] Keeping duplicate %s (same as %s): %v", path, original, err)
+	} else if debug {
+		cyan := color.New(color.FgCyan)
+		BarPrintf(bar, cyan, "[DEBUG] Deduplicated %s -> %s", path, original)
+	}
+
+	writeDedupManifest(bar, key, path, original, status)
+}
+
+// sameFile reports whether both paths refer to the same file
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
+
+// writeDedupManifest appends a duplicate mapping to the manifest, creating it on first use.
+// Caller must hold dedupLock.
+func writeDedupManifest(bar *progressbar.ProgressBar, sum, duplicate, original, status string) {
+	if dedupManifest == nil {
+		f, err := os.OpenFile(filepath.Join(outputPath, dedupManifestName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			red := color.New(color.FgRed)
+			BarPrintf(bar, red, "Error creating dedup manifest: %v", err)
+			return
+		}
+		dedupManifest = f
+	}
+	fmt.Fprintf(dedupManifest, "%s\t%s\t%s\t%s\n", sum, duplicate, original, status)
+}
+
+// closeDedupManifest closes the dedup manifest if it was opened
+func closeDedupManifest() {
+	dedupLock.Lock()
+	defer dedupLock.Unlock()
+	if dedupManifest != nil {
+		dedupManifest.Close()
+		dedupManifest = nil
+	}
+}
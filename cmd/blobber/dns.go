@@ -0,0 +1,49 @@
+package blobber
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+// dnsResolver is used for the account DNS precheck
+var dnsResolver = net.DefaultResolver
+
+// newResolver returns a resolver that sends all queries to addr (host or host:port, IPv4 or IPv6).
+// An empty addr returns the system resolver.
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// domainExists checks if a domain exists using DNS lookup.
+// It returns false with a nil error only for a definitive NXDOMAIN; timeouts and
+// other resolver failures are returned as errors so the caller doesn't skip valid accounts.
+func domainExists(domain string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsTimeout)
+	defer cancel()
+
+	_, err := dnsResolver.LookupHost(ctx, domain)
+	if err == nil {
+		return true, nil
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,102 @@
+package blobber
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"blobber/pkg/azure"
+)
+
+var (
+	nameRegex    *regexp.Regexp // Compiled --name-regex pattern, nil when not set
+	contentTypes []string       // Lowercased --content-type MIME prefixes
+	sinceTime    time.Time      // Parsed --since time, zero when not set
+	tiers        []string       // Lowercased --tier access tiers
+	maxDepth     = -1           // --max-depth, the number of "/" allowed in a blob name, -1 for no limit
+)
+
+// sinceLayouts are the accepted --since formats, tried in order
+var sinceLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseSince parses a --since value given as RFC3339 or a plain date (UTC)
+func parseSince(value string) (time.Time, error) {
+	for _, layout := range sinceLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC3339 time (2024-01-02T15:04:05Z) or a date (2024-01-02)", value)
+}
+
+// filtersEnabled reports whether any blob filter is configured
+func filtersEnabled() bool {
+	return nameRegex != nil || len(contentTypes) > 0 || !sinceTime.IsZero() || len(tiers) > 0 || maxDepth >= 0
+}
+
+// filterBlobs returns the blobs matching all configured filters (AND semantics).
+// Filters run before --limit is applied, so the limit counts matching blobs only.
+func filterBlobs(blobs []azure.Blob) []azure.Blob {
+	if !filtersEnabled() {
+		return blobs
+	}
+
+	filtered := make([]azure.Blob, 0, len(blobs))
+	for _, blob := range blobs {
+		if matchesFilters(blob) {
+			filtered = append(filtered, blob)
+		}
+	}
+	return filtered
+}
+
+// matchesFilters checks a single blob against every configured filter
+func matchesFilters(blob azure.Blob) bool {
+	if nameRegex != nil && !nameRegex.MatchString(blob.Name) {
+		return false
+	}
+
+	// Depth 0 keeps root objects only, depth 1 also objects one "directory" down, and so on
+	if maxDepth >= 0 && strings.Count(blob.Name, "/") > maxDepth {
+		return false
+	}
+
+	if len(contentTypes) > 0 && !hasAnyPrefix(strings.ToLower(blob.Properties.ContentType), contentTypes) {
+		return false
+	}
+
+	if len(tiers) > 0 && !slices.Contains(tiers, strings.ToLower(blob.Properties.AccessTier)) {
+		return false
+	}
+
+	// Blobs with a missing or unparsable Last-Modified are kept rather than silently dropped
+	if !sinceTime.IsZero() {
+		if modified, err := http.ParseTime(blob.Properties.LastModified); err == nil && !modified.After(sinceTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isArchived reports whether a blob is in the Archive tier and can't be read before rehydration
+func isArchived(blob azure.Blob) bool {
+	return strings.EqualFold(blob.Properties.AccessTier, "Archive")
+}
+
+// hasAnyPrefix reports whether s starts with any of the prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
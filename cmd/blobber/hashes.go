@@ -0,0 +1,120 @@
+package blobber
+
+import (
+	"bufio"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// hashAlgorithms are the algorithms supported by --hash-algorithm
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var (
+	hashEntries = make(map[string]string) // Escaped manifest path -> manifest line
+	hashLock    sync.Mutex
+)
+
+// hashManifestPath returns the manifest location, e.g. SHA256SUMS under the output directory
+func hashManifestPath() string {
+	return filepath.Join(outputPath, strings.ToUpper(hashAlgorithm)+"SUMS")
+}
+
+// recordHash adds a downloaded file to the hash manifest
+func recordHash(path string, sum []byte) {
+	rel, err := filepath.Rel(outputPath, path)
+	if err != nil {
+		rel = path
+	}
+	name, escaped := escapeSumName(filepath.ToSlash(rel))
+
+	// Same layout as sha256sum: escaped lines start with a backslash, two spaces mean text mode
+	line := hex.EncodeToString(sum) + "  " + name
+	if escaped {
+		line = `\` + line
+	}
+
+	hashLock.Lock()
+	defer hashLock.Unlock()
+	hashEntries[name] = line
+}
+
+// escapeSumName escapes backslashes and newlines in a file name the way sha256sum does
+func escapeSumName(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(name), true
+}
+
+// splitSumLine splits a manifest line into the hex digest and the file name, still escaped
+func splitSumLine(line string) (string, string, bool) {
+	sum, name, ok := strings.Cut(strings.TrimPrefix(line, `\`), " ")
+	if !ok || len(name) < 2 {
+		return "", "", false
+	}
+	return sum, name[1:], true // Skip the text/binary mode marker
+}
+
+// unescapeSumName reverses escapeSumName for a line that started with a backslash
+func unescapeSumName(name string) string {
+	return strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r").Replace(name)
+}
+
+// closeHashManifest writes the hash manifest, keeping entries from previous runs for files
+// that weren't part of this one. Lines are sorted by path so reruns produce stable output.
+func closeHashManifest() error {
+	hashLock.Lock()
+	defer hashLock.Unlock()
+	if len(hashEntries) == 0 {
+		return nil
+	}
+
+	path := hashManifestPath()
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if _, name, ok := splitSumLine(line); ok {
+				if _, ok := hashEntries[name]; !ok {
+					hashEntries[name] = line
+				}
+			}
+		}
+		f.Close()
+	}
+
+	names := make([]string, 0, len(hashEntries))
+	for name := range hashEntries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create hash manifest: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, name := range names {
+		fmt.Fprintln(w, hashEntries[name])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write hash manifest: %w", err)
+	}
+	return f.Close()
+}
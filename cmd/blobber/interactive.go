@@ -0,0 +1,275 @@
+package blobber
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// interactive opens a line-based browser over the found containers after a --list scan
+var interactive bool
+
+// foundContainer is a found container kept for the interactive browser
+type foundContainer struct {
+	azure.AccessResult
+	Domain  string
+	Listing []azure.Blob // Listed blobs after filters and --limit
+	marked  map[int]bool // Indexes into Listing to download
+}
+
+var (
+	browsable     []*foundContainer
+	browsableLock sync.Mutex
+)
+
+// rememberContainer keeps a found container and its listed blobs for the browser
+func rememberContainer(result azure.AccessResult, domain string, blobs []azure.Blob) {
+	browsableLock.Lock()
+	defer browsableLock.Unlock()
+	browsable = append(browsable, &foundContainer{
+		AccessResult: result,
+		Domain:       domain,
+		Listing:      blobs,
+		marked:       make(map[int]bool),
+	})
+}
+
+// browse reads commands from in until it is closed or the user quits
+func browse(in io.Reader) {
+	yellow := color.New(color.FgYellow)
+	if len(browsable) == 0 {
+		fmt.Fprintln(status, yellow.Sprint("No containers to browse."))
+		return
+	}
+	sort.Slice(browsable, func(i, j int) bool {
+		return browsableLabel(browsable[i]) < browsableLabel(browsable[j])
+	})
+
+	red := color.New(color.FgRed)
+	var current *foundContainer // Open container, nil at the container list
+	showContainers()
+
+	scanner := bufio.NewScanner(in)
+	for {
+		if current == nil {
+			fmt.Fprint(status, "containers> ")
+		} else {
+			fmt.Fprintf(status, "%s> ", browsableLabel(current))
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(status)
+			return
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch command := fields[0]; {
+		case command == "q" || command == "quit":
+			return
+		case command == "h" || command == "help" || command == "?":
+			showBrowseHelp(current != nil)
+		case command == "d" || command == "download":
+			downloadMarked()
+		case command == "l" || command == "ls":
+			if current == nil {
+				showContainers()
+			} else {
+				showBlobs(current)
+			}
+		case command == "b" || command == "back":
+			current = nil
+			showContainers()
+		case command == "m" || command == "mark":
+			if current == nil {
+				fmt.Fprintln(status, red.Sprint("Open a container first."))
+				continue
+			}
+			indexes, err := parseSelection(fields[1:], len(current.Listing))
+			if err != nil {
+				fmt.Fprintln(status, red.Sprintf("Error: %v", err))
+				continue
+			}
+			for _, i := range indexes {
+				if current.marked[i] {
+					delete(current.marked, i)
+				} else {
+					current.marked[i] = true
+				}
+			}
+			fmt.Fprintln(status, yellow.Sprintf("%d blob(s) marked in %s.", len(current.marked), browsableLabel(current)))
+		default:
+			n, err := strconv.Atoi(command)
+			if err != nil {
+				fmt.Fprintln(status, red.Sprintf("Unknown command %q, type h for help.", command))
+				continue
+			}
+			if current == nil {
+				if n < 1 || n > len(browsable) {
+					fmt.Fprintln(status, red.Sprintf("No container %d.", n))
+					continue
+				}
+				current = browsable[n-1]
+				showBlobs(current)
+			} else {
+				if n < 1 || n > len(current.Listing) {
+					fmt.Fprintln(status, red.Sprintf("No blob %d.", n))
+					continue
+				}
+				showBlobMetadata(current, current.Listing[n-1])
+			}
+		}
+	}
+}
+
+// browsableLabel names a found container in the browser
+func browsableLabel(c *foundContainer) string {
+	return containerLabel(c.Account, c.Container, c.Domain)
+}
+
+func showBrowseHelp(inContainer bool) {
+	if inContainer {
+		fmt.Fprintln(status, "  N          Show the metadata of blob N")
+		fmt.Fprintln(status, "  m N...     Mark or unmark blobs, e.g. m 1 3-5, or m all")
+		fmt.Fprintln(status, "  l          List the blobs again")
+		fmt.Fprintln(status, "  b          Back to the container list")
+	} else {
+		fmt.Fprintln(status, "  N          Open container N")
+		fmt.Fprintln(status, "  l          List the containers again")
+	}
+	fmt.Fprintf(status, "  d          Download the marked blobs of all containers to %s\n", downloadDir())
+	fmt.Fprintln(status, "  q          Quit")
+}
+
+// downloadDir describes where marked blobs are saved, the --output directory if set
+func downloadDir() string {
+	if outputPath == "" {
+		return "the current directory"
+	}
+	return outputPath
+}
+
+func showContainers() {
+	cyan := color.New(color.FgCyan)
+	fmt.Fprintln(status, cyan.Sprintf("Found %d container(s), type a number to open one or h for help:", len(browsable)))
+	for i, c := range browsable {
+		marked := ""
+		if len(c.marked) > 0 {
+			marked = fmt.Sprintf(", %d marked", len(c.marked))
+		}
+		fmt.Fprintf(status, "%4d) %s (%d blobs listed%s)\n", i+1, browsableLabel(c), len(c.Listing), marked)
+	}
+}
+
+func showBlobs(c *foundContainer) {
+	cyan := color.New(color.FgCyan)
+	fmt.Fprintln(status, cyan.Sprintf("%s: %d blob(s) listed of %d", browsableLabel(c), len(c.Listing), c.BlobCount))
+	for i, blob := range c.Listing {
+		mark := " "
+		if c.marked[i] {
+			mark = "x"
+		}
+		fmt.Fprintf(status, "%4d) [%s] %s (%d bytes)\n", i+1, mark, blob.Name, blob.Properties.ContentLength)
+	}
+}
+
+func showBlobMetadata(c *foundContainer, blob azure.Blob) {
+	p := blob.Properties
+	fields := []struct{ name, value string }{
+		{"Name", blob.Name},
+		{"URL", fmt.Sprintf("https://%s.%s/%s/%s", c.Account, c.Domain, c.Container, blob.Name)},
+		{"Size", strconv.FormatInt(p.ContentLength, 10)},
+		{"Content-Type", p.ContentType},
+		{"Content-Encoding", p.ContentEncoding},
+		{"Last-Modified", p.LastModified},
+		{"Created", p.CreationTime},
+		{"Blob type", p.BlobType},
+		{"Access tier", p.AccessTier},
+		{"ETag", p.Etag},
+		{"Content-MD5", p.ContentMD5},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			fmt.Fprintf(status, "  %-17s %s\n", f.name+":", f.value)
+		}
+	}
+}
+
+// parseSelection parses blob numbers and ranges such as "1 3-5" or "all" into 0-based indexes
+func parseSelection(args []string, n int) ([]int, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no blobs given, e.g. m 1 3-5")
+	}
+	var indexes []int
+	for _, arg := range args {
+		if arg == "all" {
+			all := make([]int, n)
+			for i := range all {
+				all[i] = i
+			}
+			return all, nil
+		}
+
+		first, last, isRange := strings.Cut(arg, "-")
+		from, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blob number %q", arg)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(last); err != nil {
+				return nil, fmt.Errorf("invalid blob range %q", arg)
+			}
+		}
+		if from < 1 || to > n || from > to {
+			return nil, fmt.Errorf("blob range %q is outside 1-%d", arg, n)
+		}
+		for i := from; i <= to; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+	return indexes, nil
+}
+
+// downloadMarked downloads the marked blobs through the shared download pool and clears the marks
+func downloadMarked() {
+	var wg sync.WaitGroup
+	total := 0
+	for _, c := range browsable {
+		if len(c.marked) == 0 {
+			continue
+		}
+		blobs := make([]azure.Blob, 0, len(c.marked))
+		for i, blob := range c.Listing {
+			if c.marked[i] {
+				blobs = append(blobs, blob)
+			}
+		}
+		total += len(blobs)
+		c.marked = make(map[int]bool)
+
+		wg.Add(1)
+		go func(c *foundContainer, blobs []azure.Blob) {
+			defer wg.Done()
+			// The scan's --deadline may have passed, marked downloads run to completion
+			downloadBlobs(context.Background(), c.Account, c.Container, c.Domain, blobs)
+		}(c, blobs)
+	}
+
+	if total == 0 {
+		yellow := color.New(color.FgYellow)
+		fmt.Fprintln(status, yellow.Sprint("No blobs marked, open a container and use m to mark some."))
+		return
+	}
+	wg.Wait()
+}
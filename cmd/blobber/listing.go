@@ -0,0 +1,88 @@
+package blobber
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// dfsBaseDomain is the default base domain in --adls mode
+const dfsBaseDomain = "dfs.core.windows.net"
+
+// listRequestURL builds the listing URL of a container, continuing from marker when set.
+// In --adls mode the container is an ADLS Gen2 filesystem listed through the dfs endpoint.
+func listRequestURL(account, container, domain, marker string) string {
+	if adls {
+		listURL := fmt.Sprintf("https://%s.%s/%s?resource=filesystem&recursive=true", account, domain, container)
+		if marker != "" {
+			listURL += "&continuation=" + url.QueryEscape(marker)
+		}
+		return listURL
+	}
+
+	listURL := fmt.Sprintf("https://%s.%s/%s?restype=container&comp=list", account, domain, container)
+	if marker != "" {
+		listURL += "&marker=" + url.QueryEscape(marker)
+	}
+	return listURL
+}
+
+// parseListing parses a listing response from the configured endpoint into a common page,
+// so blob (XML) and dfs (JSON) listings feed the same download pipeline
+func parseListing(resp *http.Response, body []byte) (azure.ListPage, error) {
+	if adls {
+		return azure.ParsePathList(body, resp.Header.Get("x-ms-continuation"))
+	}
+	return azure.ParseBlobList(body)
+}
+
+// listRetries is how many times a malformed listing is requested again before giving up
+const listRetries = 2
+
+// fetchListing requests a listing URL and reads the whole body
+func fetchListing(listURL string) (*http.Response, []byte, error) {
+	resp, err := httpGet(listURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// fetchPage requests one page of a container listing continuing from marker
+func fetchPage(account, container, domain, marker string) (azure.ListPage, error) {
+	pageURL := listRequestURL(account, container, domain, marker)
+
+	if debug {
+		cyan := color.New(color.FgCyan)
+		BarPrintf(mainProgressBar, cyan, "[DEBUG] Fetching next marker: %s", pageURL)
+	}
+
+	sleepJitter()
+	resp, err := httpGet(pageURL)
+	if err != nil {
+		return azure.ListPage{}, fmt.Errorf("error fetching next marker: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return azure.ListPage{}, fmt.Errorf("error reading next marker response: %w", err)
+	}
+
+	page, err := parseListing(resp, body)
+	if err != nil {
+		return azure.ListPage{}, fmt.Errorf("error parsing next marker response: %w", err)
+	}
+	return page, nil
+}
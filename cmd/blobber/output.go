@@ -0,0 +1,162 @@
+package blobber
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+
+	"blobber/pkg/azure"
+	"blobber/pkg/output"
+
+	"github.com/fatih/color"
+)
+
+var (
+	sink     *output.Sink // Receives listed blobs in --list and --output modes, nil otherwise
+	sinkPath string       // Output file of the sink, empty for the console
+	pipeList bool         // --list writes plain URLs to a redirected stdout instead of above the bar
+)
+
+// barWriter prints sink output above the main progress bar. Only complete lines are printed,
+// since the bar redrawn below a partial line would erase it. The sink serializes writes.
+type barWriter struct {
+	pending []byte
+}
+
+func (b *barWriter) Write(p []byte) (int, error) {
+	b.pending = append(b.pending, p...)
+	if i := bytes.LastIndexByte(b.pending, '\n'); i >= 0 {
+		printAbove(mainProgressBar, string(b.pending[:i+1]))
+		b.pending = append(b.pending[:0], b.pending[i+1:]...)
+	}
+	return len(p), nil
+}
+
+// listPath is the file blob lists are written to, empty for the console. In --interactive
+// mode --output is the directory marked blobs are downloaded to instead.
+func listPath() string {
+	if interactive {
+		return ""
+	}
+	return outputPath
+}
+
+// openSink creates the sink shared by all containers of a run: the --output file, which a
+// .gz name or --compress-output makes gzip-compressed, or the console for --list
+func openSink(factory output.Factory) (*output.Sink, string, error) {
+	if listPath() == "" {
+		if pipeList {
+			return output.NewSink(factory(os.Stdout)), "", nil
+		}
+		return output.NewSink(factory(&barWriter{})), "", nil
+	}
+
+	path := outputPath
+	if compressOutput && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating output file: %w", err)
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return output.NewSink(factory(file), file), path, nil
+	}
+
+	// Closing the gzip writer flushes the remaining data and writes the footer before the file closes
+	gz := gzip.NewWriter(file)
+	return output.NewSink(factory(gz), gz, file), path, nil
+}
+
+// writeBlobs sends the blobs of a container to the sink
+func writeBlobs(account, container, domain string, blobs []azure.Blob) {
+	records := make([]output.Record, 0, len(blobs))
+	for _, blob := range blobs {
+		records = append(records, output.Record{
+			Account:      account,
+			Container:    container,
+			Domain:       domain,
+			URL:          fmt.Sprintf("https://%s.%s/%s/%s", account, domain, container, blob.Name),
+			Name:         blob.Name,
+			Size:         blob.Properties.ContentLength,
+			ContentType:  blob.Properties.ContentType,
+			LastModified: blob.Properties.LastModified,
+		})
+	}
+
+	if err := sink.Write(records...); err != nil {
+		red := color.New(color.FgRed)
+		BarPrintf(mainProgressBar, red, "Error writing output: %v", err)
+		return
+	}
+
+	if sinkPath != "" {
+		green := color.New(color.FgGreen)
+		BarPrintf(mainProgressBar, green, "Saved %d blob URLs from %s to %s", len(records), containerLabel(account, container, domain), sinkPath)
+	}
+}
+
+// writeSensitiveFiles sends the readable well-known files of a container to a structured sink
+// as findingSensitiveFile records. Plain text lists hold listed blob URLs only, which these
+// could repeat, and the files are already reported by [SENSITIVE] lines.
+func writeSensitiveFiles(account, container, domain string, files []azure.SensitiveFile) {
+	if sink == nil || format == "text" || len(files) == 0 {
+		return
+	}
+
+	records := make([]output.Record, 0, len(files))
+	for _, file := range files {
+		records = append(records, findingRecord(account, container, domain, file.URL, file.Size, findingSensitiveFile))
+	}
+	writeFindings(records)
+}
+
+// writeProbedBlobs sends blobs found by --probe in a non-listable container to the sink as
+// findingBlobAccessible records. No listing includes them, so plain text lists get them too.
+func writeProbedBlobs(account, container, domain string, urls []string) {
+	if sink == nil || len(urls) == 0 {
+		return
+	}
+
+	records := make([]output.Record, 0, len(urls))
+	for _, blobURL := range urls {
+		records = append(records, findingRecord(account, container, domain, blobURL, -1, findingBlobAccessible))
+	}
+	writeFindings(records)
+}
+
+// findingRecord builds the record of a blob found other than by listing. size is -1 if unknown.
+func findingRecord(account, container, domain, blobURL string, size int64, finding string) output.Record {
+	prefix := fmt.Sprintf("https://%s.%s/%s/", account, domain, container)
+	return output.Record{
+		Account:   account,
+		Container: container,
+		Domain:    domain,
+		URL:       blobURL,
+		Name:      strings.TrimPrefix(blobURL, prefix),
+		Size:      size,
+		Finding:   finding,
+	}
+}
+
+// writeFindings sends finding records to the sink
+func writeFindings(records []output.Record) {
+	if err := sink.Write(records...); err != nil {
+		red := color.New(color.FgRed)
+		BarPrintf(mainProgressBar, red, "Error writing output: %v", err)
+	}
+}
+
+// closeSink finishes the output file, e.g. the closing bracket of a JSON array
+func closeSink() {
+	if sink == nil {
+		return
+	}
+	if err := sink.Close(); err != nil {
+		red := color.New(color.FgRed)
+		fmt.Fprintln(status, red.Sprintf("Error writing output file: %v", err))
+	}
+}
@@ -0,0 +1,125 @@
+package blobber
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blobber/pkg/azure"
+	"blobber/pkg/output"
+)
+
+// setOutput points the sink at path for one test and silences printed messages
+func setOutput(t *testing.T, path string, compress bool) {
+	t.Helper()
+	oldPath, oldCompress, oldTerminal := outputPath, compressOutput, terminal
+	outputPath, compressOutput, terminal = path, compress, io.Discard
+	t.Cleanup(func() {
+		outputPath, compressOutput, terminal = oldPath, oldCompress, oldTerminal
+		sink, sinkPath = nil, ""
+	})
+}
+
+// writeTestBlobs writes n blobs of one container through openSink and closes it
+func writeTestBlobs(t *testing.T, factory output.Factory, n int) string {
+	t.Helper()
+	var err error
+	sink, sinkPath, err = openSink(factory)
+	if err != nil {
+		t.Fatalf("openSink: %v", err)
+	}
+
+	blobs := make([]azure.Blob, n)
+	for i := range blobs {
+		blobs[i].Name = "dir/file" + strings.Repeat("x", i) + ".txt"
+	}
+	writeBlobs("acme", "public", "blob.core.windows.net", blobs)
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("closing sink: %v", err)
+	}
+	return sinkPath
+}
+
+// readGzip decompresses a whole file, failing if the gzip footer is missing
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("%s is not gzip-compressed: %v", path, err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading %s: %v (truncated or missing footer?)", path, err)
+	}
+	return string(data)
+}
+
+func TestOpenSinkGzipName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt.gz")
+	setOutput(t, path, false)
+
+	if got := writeTestBlobs(t, output.NewText, 3); got != path {
+		t.Errorf("sink path = %q, want %q", got, path)
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(strings.NewReader(readGzip(t, path)))
+	for scanner.Scan() {
+		urls = append(urls, scanner.Text())
+	}
+	if len(urls) != 3 {
+		t.Fatalf("got %d URLs, want 3: %q", len(urls), urls)
+	}
+	if want := "https://acme.blob.core.windows.net/public/dir/file.txt"; urls[0] != want {
+		t.Errorf("first URL = %q, want %q", urls[0], want)
+	}
+}
+
+func TestOpenSinkCompressOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.json")
+	setOutput(t, path, true)
+
+	got := writeTestBlobs(t, output.NewJSON, 2)
+	if got != path+".gz" {
+		t.Errorf("sink path = %q, want %q", got, path+".gz")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("uncompressed %s was created", path)
+	}
+
+	// The closing bracket is only written when the sink closes
+	var records []output.Record
+	if err := json.Unmarshal([]byte(readGzip(t, got)), &records); err != nil {
+		t.Fatalf("output isn't a complete JSON array: %v", err)
+	}
+	if len(records) != 2 {
+		t.Errorf("got %d records, want 2", len(records))
+	}
+}
+
+func TestOpenSinkPlain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "urls.txt")
+	setOutput(t, path, false)
+
+	writeTestBlobs(t, output.NewText, 2)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\n") != 2 || !strings.HasPrefix(string(data), "https://") {
+		t.Errorf("plain output = %q, want 2 URL lines", data)
+	}
+}
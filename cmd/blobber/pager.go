@@ -0,0 +1,138 @@
+package blobber
+
+import (
+	"fmt"
+	"sync"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// pager runs deep pagination (NextMarker following) in its own bounded pool, so large
+// containers don't hold scan slots, and reports combined progress on the main bar
+// instead of one bar per container fighting for the terminal.
+type pager struct {
+	sem         chan struct{}
+	mu          sync.Mutex
+	description string // Main bar description to extend with paging stats
+	active      int    // Containers currently paging
+	fetched     int    // Blobs fetched by deep pagination so far
+}
+
+// pages is the global deep pagination pool
+var pages *pager
+
+// newPager creates a pagination pool running at most size containers at once
+func newPager(size int, description string) *pager {
+	if size < 1 {
+		size = 1
+	}
+	return &pager{
+		sem:         make(chan struct{}, size),
+		description: description,
+	}
+}
+
+// acquire waits for a pagination slot
+func (p *pager) acquire() {
+	p.sem <- struct{}{}
+	p.mu.Lock()
+	p.active++
+	p.describe()
+	p.mu.Unlock()
+}
+
+// release returns a pagination slot
+func (p *pager) release() {
+	p.mu.Lock()
+	p.active--
+	p.describe()
+	p.mu.Unlock()
+	<-p.sem
+}
+
+// add records fetched blobs in the combined progress view
+func (p *pager) add(n int) {
+	p.mu.Lock()
+	p.fetched += n
+	p.describe()
+	p.mu.Unlock()
+}
+
+// describe updates the main bar description. Caller must hold p.mu.
+func (p *pager) describe() {
+	if p.active == 0 {
+		mainProgressBar.Describe(p.description)
+		return
+	}
+	mainProgressBar.Describe(fmt.Sprintf("%s | paging %d container(s), %d blobs fetched", p.description, p.active, p.fetched))
+}
+
+// cursor tracks the pagination of one container across pageThrough calls
+type cursor struct {
+	marker string          // Marker of the next page, empty after the last page
+	seen   map[string]bool // Markers already requested, to detect loops
+	pages  int             // Pages fetched so far, including the first
+}
+
+// newCursor creates a cursor continuing after the first page of a listing
+func newCursor(marker string) *cursor {
+	return &cursor{marker: marker, seen: make(map[string]bool), pages: 1}
+}
+
+// pageThrough follows NextMarkers from the cursor inside the pagination pool. Listed blobs
+// are kept while fewer than keep are held; paging stops after the last page, or once keep
+// blobs are held unless countAll is set. A repeated marker or --max-pages ends the listing
+// for good. It returns the kept blobs and the number of blobs listed.
+func (p *pager) pageThrough(account, container, domain string, cur *cursor, keep int, countAll bool) ([]azure.Blob, int) {
+	p.acquire()
+	defer p.release()
+
+	target := containerLabel(account, container, domain)
+	var blobs []azure.Blob
+	count := 0
+	for cur.marker != "" && (countAll || len(blobs) < keep) {
+		if cur.seen[cur.marker] {
+			yellow := color.New(color.FgYellow)
+			BarPrintf(mainProgressBar, yellow, "[WARN] %s: NextMarker %q repeated, stopping pagination", target, cur.marker)
+			cur.marker = ""
+			break
+		}
+		if maxPages > 0 && cur.pages >= maxPages {
+			yellow := color.New(color.FgYellow)
+			BarPrintf(mainProgressBar, yellow, "[WARN] %s: Reached --max-pages %d, listing is incomplete", target, maxPages)
+			cur.marker = ""
+			break
+		}
+		cur.seen[cur.marker] = true
+
+		page, err := fetchPage(account, container, domain, cur.marker)
+		if err != nil {
+			if debug {
+				red := color.New(color.FgRed)
+				BarPrintf(mainProgressBar, red, "[DEBUG] %s: %v", target, err)
+			}
+			cur.marker = ""
+			return blobs, count
+		}
+		cur.pages++
+
+		listed := page.Blobs
+		count += len(listed)
+		if room := keep - len(blobs); room > 0 {
+			if len(listed) > room {
+				listed = listed[:room]
+			}
+			blobs = append(blobs, listed...)
+		}
+		p.add(len(page.Blobs))
+		cur.marker = page.NextMarker
+
+		if debug {
+			cyan := color.New(color.FgCyan)
+			BarPrintf(mainProgressBar, cyan, "[DEBUG] %s: %d more blobs listed so far", target, count)
+		}
+	}
+	return blobs, count
+}
@@ -0,0 +1,69 @@
+package blobber
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// listingPage writes a one-blob listing page named after marker, continuing at next
+func listingPage(w http.ResponseWriter, marker, next string) {
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="https://acme.blob.core.windows.net/" ContainerName="public"><Blobs><Blob><Name>%s.txt</Name><Properties><Content-Length>1</Content-Length></Properties></Blob></Blobs><NextMarker>%s</NextMarker></EnumerationResults>`, marker, next)
+}
+
+// setMaxPages sets --max-pages for one test
+func setMaxPages(t *testing.T, n int) {
+	t.Helper()
+	old := maxPages
+	maxPages = n
+	t.Cleanup(func() { maxPages = old })
+}
+
+func TestPageThroughRepeatedMarker(t *testing.T) {
+	setMaxPages(t, 0) // No cap, so only loop detection can stop the listing
+	requests := fakeStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		// A broken server handing out the same marker forever
+		listingPage(w, r.URL.Query().Get("marker"), "m1")
+	})
+
+	cur := newCursor("m1")
+	blobs, count := newPager(1, "test").pageThrough("acme", "public", testDomain, cur, 100, true)
+	if got := requests.count(); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+	if count != 1 || len(blobs) != 1 {
+		t.Errorf("listed %d blobs, kept %d, want 1 and 1", count, len(blobs))
+	}
+	if cur.marker != "" {
+		t.Errorf("cursor marker = %q, want the listing ended", cur.marker)
+	}
+
+	// The ended cursor must not resume paging
+	newPager(1, "test").pageThrough("acme", "public", testDomain, cur, 100, true)
+	if got := requests.count(); got != 1 {
+		t.Errorf("got %d requests after the listing ended, want 1", got)
+	}
+}
+
+func TestPageThroughMaxPages(t *testing.T) {
+	setMaxPages(t, 3)
+	requests := fakeStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		// An endless listing with a new marker on every page
+		n, _ := strconv.Atoi(r.URL.Query().Get("marker"))
+		listingPage(w, strconv.Itoa(n), strconv.Itoa(n+1))
+	})
+
+	// The first page was fetched by checkContainer, so two more are allowed
+	cur := newCursor("1")
+	blobs, count := newPager(1, "test").pageThrough("acme", "public", testDomain, cur, 100, true)
+	if got := requests.count(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+	if count != 2 || len(blobs) != 2 {
+		t.Errorf("listed %d blobs, kept %d, want 2 and 2", count, len(blobs))
+	}
+	if cur.pages != 3 || cur.marker != "" {
+		t.Errorf("cursor = %d pages, marker %q, want 3 pages and the listing ended", cur.pages, cur.marker)
+	}
+}
@@ -0,0 +1,95 @@
+package blobber
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	maxPatternExpansion  = 1000000   // Patterns expanding beyond this are rejected
+	warnPatternExpansion = 10000     // Scans of more combinations than this print a warning
+	maxScanCombinations  = 100000000 // Scans of more combinations than this are rejected
+)
+
+// rangePattern matches a numeric range such as [01-99]
+var rangePattern = regexp.MustCompile(`\[(\d+)-(\d+)\]`)
+
+// expandPattern expands numeric ranges in a name, e.g. "acme[01-03]" becomes acme01, acme02, acme03.
+// Values are zero-padded to the width of the start bound, and several ranges expand to their cross product.
+func expandPattern(pattern string) ([]string, error) {
+	loc := rangePattern.FindStringSubmatchIndex(pattern)
+	if loc == nil {
+		return []string{pattern}, nil
+	}
+
+	startText := pattern[loc[2]:loc[3]]
+	start, err := strconv.Atoi(startText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range in %q: %w", pattern, err)
+	}
+	end, err := strconv.Atoi(pattern[loc[4]:loc[5]])
+	if err != nil {
+		return nil, fmt.Errorf("invalid range in %q: %w", pattern, err)
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid range in %q: end is less than start", pattern)
+	}
+
+	// Expand the remaining ranges first so the size can be checked before building the product
+	suffixes, err := expandPattern(pattern[loc[1]:])
+	if err != nil {
+		return nil, err
+	}
+	// Compare before multiplying, the product of huge bounds can overflow
+	if end-start >= maxPatternExpansion/len(suffixes) {
+		return nil, fmt.Errorf("pattern %q expands to more than %d names", pattern, maxPatternExpansion)
+	}
+
+	prefix := pattern[:loc[0]]
+	result := make([]string, 0, (end-start+1)*len(suffixes))
+	for i := start; i <= end; i++ {
+		value := fmt.Sprintf("%0*d", len(startText), i)
+		for _, suffix := range suffixes {
+			result = append(result, prefix+value+suffix)
+		}
+	}
+	return result, nil
+}
+
+// mergePatterns appends the expansion of comma-separated patterns to list, skipping names already present
+func mergePatterns(list []string, patterns string) ([]string, error) {
+	seen := make(map[string]bool, len(list))
+	for _, item := range list {
+		seen[item] = true
+	}
+
+	for _, pattern := range splitList(patterns) {
+		expanded, err := expandPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range expanded {
+			if !seen[item] {
+				seen[item] = true
+				list = append(list, item)
+			}
+		}
+	}
+
+	if len(list) > maxPatternExpansion {
+		return nil, fmt.Errorf("patterns expand to more than %d names", maxPatternExpansion)
+	}
+	return list, nil
+}
+
+// scanCombinations returns the number of account, container and domain combinations a scan checks,
+// rejecting scans of more than maxScanCombinations
+func scanCombinations(accounts, containers, domains int) (int64, error) {
+	total := int64(accounts) * int64(containers) * int64(domains)
+	if total > maxScanCombinations {
+		return 0, fmt.Errorf("%d account(s) x %d container(s) x %d domain(s) is %d combinations, more than %d",
+			accounts, containers, domains, total, maxScanCombinations)
+	}
+	return total, nil
+}
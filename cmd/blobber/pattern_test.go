@@ -0,0 +1,61 @@
+package blobber
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		err     string
+	}{
+		{"acme", []string{"acme"}, ""},
+		{"acme[08-10]", []string{"acme08", "acme09", "acme10"}, ""},
+		{"a[1-2]b[0-1]", []string{"a1b0", "a1b1", "a2b0", "a2b1"}, ""},
+		{"a[3-1]", nil, "end is less than start"},
+		{"a[0-999999]", nil, ""},
+		{"a[0-1000000]", nil, "more than 1000000 names"},
+		{"a[0-999]b[0-1000]", nil, "more than 1000000 names"},
+		// The product of these bounds wraps around to a small positive number in 64 bits
+		{"a[0-4611686018427387904]b[0-3]", nil, "more than 1000000 names"},
+		{"a[0-9223372036854775806]", nil, "more than 1000000 names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := expandPattern(tt.pattern)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expandPattern() error = %v, want it to contain %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandPattern() error = %v", err)
+			}
+			if tt.want != nil && strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("expandPattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanCombinations(t *testing.T) {
+	tests := []struct {
+		accounts, containers, domains int
+		want                          int64
+		ok                            bool
+	}{
+		{10, 10, 1, 100, true},
+		{9999, 9999, 1, 99980001, true},
+		{9999, 9999, 2, 0, false},
+		{999999, 999999, 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := scanCombinations(tt.accounts, tt.containers, tt.domains)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("scanCombinations(%d, %d, %d) = %d, %v, want %d, ok %v", tt.accounts, tt.containers, tt.domains, got, err, tt.want, tt.ok)
+		}
+	}
+}
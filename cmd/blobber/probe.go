@@ -0,0 +1,135 @@
+package blobber
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// findingBlobAccessible marks a blob that can be read directly although its container can't be listed
+const findingBlobAccessible = "blob-accessible"
+
+// findingSensitiveFile marks a readable well-known file found by --probe-well-known
+const findingSensitiveFile = "sensitive-file"
+
+// wellKnownFiles are the high-value names requested by --probe-well-known in every found container
+var wellKnownFiles = []string{
+	".env",
+	"web.config",
+	"appsettings.json",
+	"appsettings.Development.json",
+	".git/config",
+	".git-credentials",
+	".aws/credentials",
+	".npmrc",
+	"backup.sql",
+	"dump.sql",
+	"id_rsa",
+	"id_ed25519",
+	"credentials",
+}
+
+var (
+	probeWellKnown bool     // Request wellKnownFiles in found containers
+	sensitiveFound int      // Readable well-known file counter, guarded by foundBlobsLock
+	probeNames     []string // Candidate blob names from --probe
+	foundBlobs     int      // Directly accessible blob counter
+	foundBlobsLock sync.Mutex
+)
+
+// probeBlobs requests candidate blob names directly and returns the URLs that respond with HTTP 200.
+// At most maxProbes names are tried per container.
+func probeBlobs(account, container, domain string, names []string) []string {
+	if maxProbes > 0 && len(names) > maxProbes {
+		names = names[:maxProbes]
+	}
+
+	var hits []string
+	for _, name := range names {
+		blobURL := fmt.Sprintf("https://%s.%s/%s/%s", account, domain, container, name)
+
+		if debug {
+			cyan := color.New(color.FgCyan)
+			BarPrintf(mainProgressBar, cyan, "[DEBUG] Probing: %s", blobURL)
+		}
+
+		resp, err := httpGet(blobURL)
+		if err != nil {
+			if debug {
+				red := color.New(color.FgRed)
+				BarPrintf(mainProgressBar, red, "[DEBUG] Error probing %s: %v", blobURL, err)
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		green := color.New(color.FgGreen)
+		BarPrintf(mainProgressBar, green, "[FOUND] %s %s", findingBlobAccessible, blobURL)
+		hits = append(hits, blobURL)
+	}
+
+	if len(hits) > 0 {
+		foundBlobsLock.Lock()
+		foundBlobs += len(hits)
+		foundBlobsLock.Unlock()
+	}
+	return hits
+}
+
+// reportProbedBlobs sends the blobs found by --probe in a non-listable container, held in
+// result.Blobs, to the sink and the webhook
+func reportProbedBlobs(result azure.AccessResult, domain string) {
+	if len(result.Blobs) == 0 {
+		return
+	}
+	writeProbedBlobs(result.Account, result.Container, domain, result.Blobs)
+	if hook != nil {
+		hook.Notify(result, domain)
+	}
+}
+
+// probeSensitiveFiles requests the well-known sensitive file names in a found container
+// and reports the ones that respond with HTTP 200, with their size
+func probeSensitiveFiles(account, container, domain string) []azure.SensitiveFile {
+	var hits []azure.SensitiveFile
+	for _, name := range wellKnownFiles {
+		blobURL := fmt.Sprintf("https://%s.%s/%s/%s", account, domain, container, name)
+
+		resp, err := httpGet(blobURL)
+		if err != nil {
+			if debug {
+				red := color.New(color.FgRed)
+				BarPrintf(mainProgressBar, red, "[DEBUG] Error probing %s: %v", blobURL, err)
+			}
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		size := "unknown size"
+		if resp.ContentLength >= 0 {
+			size = fmt.Sprintf("%d bytes", resp.ContentLength)
+		}
+		red := color.New(color.FgRed, color.Bold)
+		BarPrintf(mainProgressBar, red, "[SENSITIVE] %s (%s)", blobURL, size)
+		hits = append(hits, azure.SensitiveFile{URL: blobURL, Size: resp.ContentLength})
+	}
+
+	if len(hits) > 0 {
+		foundBlobsLock.Lock()
+		sensitiveFound += len(hits)
+		foundBlobsLock.Unlock()
+	}
+	return hits
+}
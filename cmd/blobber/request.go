@@ -0,0 +1,52 @@
+package blobber
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// cancelBody releases a request context once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// httpGet sends a GET request bounded by --request-timeout, so a slow host
+// releases its worker before the client's overall timeout
+func httpGet(rawURL string) (*http.Response, error) {
+	return httpGetContext(context.Background(), rawURL)
+}
+
+// httpGetContext is like httpGet, also giving up when parent is done
+func httpGetContext(parent context.Context, rawURL string) (*http.Response, error) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if requestTimeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, requestTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
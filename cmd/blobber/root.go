@@ -2,83 +2,100 @@ package blobber
 
 import (
 	"bufio"
-	"crypto/tls"
-	"encoding/xml"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
-	"net"
+	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"regexp"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"blobber/pkg/azure"
+	"blobber/pkg/downloader"
+	"blobber/pkg/output"
+	"blobber/pkg/utils"
+
 	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
-// Blob structures for XML parsing
-type BlobError struct {
-	XMLName xml.Name `xml:"Error"`
-	Code    string   `xml:"Code"`
-	Message string   `xml:"Message"`
-}
-
-type BlobProperties struct {
-	CreationTime      string `xml:"Creation-Time"`
-	LastModified      string `xml:"Last-Modified"`
-	Etag              string `xml:"Etag"`
-	ContentLength     int64  `xml:"Content-Length"`
-	ContentType       string `xml:"Content-Type"`
-	ContentEncoding   string `xml:"Content-Encoding"`
-	ContentLanguage   string `xml:"Content-Language"`
-	ContentCRC64      string `xml:"Content-CRC64"`
-	ContentMD5        string `xml:"Content-MD5"`
-	CacheControl      string `xml:"Cache-Control"`
-	ContentDisposition string `xml:"Content-Disposition"`
-	BlobType          string `xml:"BlobType"`
-	AccessTier        string `xml:"AccessTier"`
-}
-
-type Blob struct {
-	XMLName    xml.Name      `xml:"Blob"`
-	Name       string        `xml:"Name"`
-	Properties BlobProperties `xml:"Properties"`
-}
-
-type Blobs struct {
-	XMLName xml.Name `xml:"Blobs"`
-	Blob    []Blob   `xml:"Blob"`
-}
-
-type EnumerationResults struct {
-	XMLName       xml.Name `xml:"EnumerationResults"`
-	ServiceEndpoint string   `xml:"ServiceEndpoint,attr"`
-	ContainerName string   `xml:"ContainerName,attr"`
-	Blobs         Blobs    `xml:"Blobs"`
-	NextMarker    string   `xml:"NextMarker"`
-}
-
 // Command line flags
 var (
 	accounts            string
 	containers          string
+	accountPattern      string
+	containerPattern    string
 	isDownload          bool
 	outputPath          string
+	outputURL           string
+	objectWriter        downloader.ObjectWriter // Remote --output-url destination, nil for local downloads
 	skipSSL             bool
+	caCert              string
+	clientCert          string
+	clientKey           string
 	maxGoroutines       int
 	maxParallelDownload int
 	debug               bool
 	listBlobs           bool
 	limit               int
 	baseDomain          string
+	baseDomains         []string
 	totalCount          bool
-	foundContainers     int // Erişilebilir container sayacı
+	dedup               bool
+	hashes              bool
+	since               string
+	aclCheck            bool
+	format              string
+	tierFilter          string
+	sampleBytes         int64
+	includeArchive      bool
+	skipExisting        bool
+	hashAlgorithm       string
+	jitter              int
+	shuffle             bool
+	seed                int64
+	noDNSCheck          bool
+	dnsTimeout          time.Duration
+	resolverAddr        string
+	namePattern         string
+	contentTypeFilter   string
+	probe               string
+	maxProbes           int
+	checkpointPath      string
+	outputTemplateText  string
+	checkOnly           bool
+	scanDeadline        time.Duration
+	compressOutput      bool
+	maxParallelPaging   int
+	maxPages            int
+	adls                bool
+	minBlobs            int
+	webhookURL          string
+	webhookTemplate     string
+	requestTimeout      time.Duration
+	clientTimeout       time.Duration
+	dialTimeout         time.Duration
+	tlsHandshakeTimeout time.Duration
+	responseHeaderTimeout time.Duration
+	rps                 float64
+	summary             azure.ScanSummary // Aggregated results of checked containers
 	foundContainerLock  sync.Mutex // Sayaç için mutex
+	suppressedContainers int // Public containers below --min-blobs, counted as public in summary
+	archiveSkipped       int // Archive-tier blobs not downloaded
 	
+	// Global download semaphore shared by all containers
+	downloadSem         chan struct{}
+
 	// Global progress bar
 	mainProgressBar     *progressbar.ProgressBar
 )
@@ -88,8 +105,7 @@ var client *http.Client
 
 // BarPrintf, progressbar'ı bozmadan renkli çıktı yazdırmak için yardımcı fonksiyon
 func BarPrintf(bar *progressbar.ProgressBar, c *color.Color, format string, a ...interface{}) {
-	coloredText := c.Sprintf(format, a...)
-	progressbar.Bprintf(bar, "%s\n", coloredText)
+	printAbove(bar, c.Sprintf(format, a...)+"\n")
 }
 
 // BarPrintln, progressbar'ı bozmadan renkli çıktı yazdırmak için yardımcı fonksiyon
@@ -103,7 +119,7 @@ func BarPrintln(bar *progressbar.ProgressBar, c *color.Color, a ...interface{})
 			args[i] = v
 		}
 	}
-	progressbar.Bprintln(bar, args...)
+	printAbove(bar, fmt.Sprintln(args...))
 }
 
 // RootCmd represents the base command when called without any subcommands
@@ -113,57 +129,285 @@ var RootCmd = &cobra.Command{
 	Long: `Blobber is a tool to check if Azure Blob Storage containers are publicly accessible.
 It can list and download files from publicly accessible containers.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// A redirected --list keeps stdout for blob URLs alone, everything else goes to stderr
+		if listBlobs && listPath() == "" && !isTerminal(os.Stdout) {
+			pipeList = true
+			statusToStderr()
+			cmd.SetOut(os.Stderr)
+		}
+
 		// Check for incompatible flags - output sadece list ile birlikte kullanılamaz
-		if outputPath != "" && listBlobs {
+		if listPath() != "" && listBlobs {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: --output cannot be used with --list parameter"))
+			return
+		}
+
+		if interactive && (!listBlobs || checkOnly) {
 			red := color.New(color.FgRed)
-			fmt.Println(red.Sprintf("Error: --output cannot be used with --list parameter"))
+			fmt.Fprintln(status, red.Sprintf("Error: --interactive requires --list and can't be used with --check-only"))
 			return
 		}
+		if outputURL != "" {
+			red := color.New(color.FgRed)
+			if !isDownload {
+				fmt.Fprintln(status, red.Sprintf("Error: --output-url requires --download"))
+				return
+			}
+			if dedup || hashes {
+				fmt.Fprintln(status, red.Sprintf("Error: --dedup and --hashes work on local files and can't be used with --output-url"))
+				return
+			}
+		}
+
+		// Compile filters up front so an invalid pattern fails before scanning
+		if namePattern != "" {
+			re, err := regexp.Compile(namePattern)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: invalid --name-regex: %v", err))
+				return
+			}
+			nameRegex = re
+		}
+		hashAlgorithm = strings.ToLower(hashAlgorithm)
+		if _, ok := hashAlgorithms[hashAlgorithm]; !ok {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: unsupported --hash-algorithm %q (use md5, sha1, sha256 or sha512)", hashAlgorithm))
+			return
+		}
+		newWriter, err := output.Get(format)
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: invalid --format: %v", err))
+			return
+		}
+		if since != "" {
+			t, err := parseSince(since)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: invalid --since: %v", err))
+				return
+			}
+			sinceTime = t
+		}
+		errorFilter = splitList(errorFilterText)
+		for _, tier := range splitList(tierFilter) {
+			tiers = append(tiers, strings.ToLower(tier))
+		}
+		for _, prefix := range splitList(contentTypeFilter) {
+			contentTypes = append(contentTypes, strings.ToLower(prefix))
+		}
 
 		// If output is specified or download is not requested, set default limit to 99999
-		if !isDownload && outputPath != ""  && limit == 10 {
+		if !isDownload && listPath() != ""  && limit == 10 {
 			limit = 99999
 		}
 
+		if skipSSL {
+			yellow := color.New(color.FgYellow)
+			fmt.Fprintln(os.Stderr, yellow.Sprint("Warning: TLS certificate verification is disabled (--skipSSL)"))
+		}
+
 		// Initialize HTTP client
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: skipSSL},
+		tlsConfig, err := utils.NewTLSConfig(utils.TLSOptions{
+			SkipVerify:     skipSSL,
+			CACertFile:     caCert,
+			ClientCertFile: clientCert,
+			ClientKeyFile:  clientKey,
+		})
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: %v", err))
+			return
+		}
+		var tr http.RoundTripper = utils.NewTransport(tlsConfig, utils.TransportOptions{
+			DialTimeout:           dialTimeout,
+			TLSHandshakeTimeout:   tlsHandshakeTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+		})
+		if rps > 0 {
+			// Every outbound request (listing, pagination, downloads) shares one limiter
+			tr = &utils.RateLimitedTransport{Base: tr, Limiter: utils.NewRateLimiter(rps)}
 		}
 		client = &http.Client{
 			Transport: tr,
-			Timeout:   time.Second * 30,
+			Timeout:   clientTimeout,
 		}
 
+		if outputURL != "" {
+			// Uploads go to the user's own storage, so they always verify TLS regardless of --skipSSL
+			uploadClient := &http.Client{
+				Transport: utils.NewTransport(nil, utils.TransportOptions{
+					DialTimeout:           dialTimeout,
+					TLSHandshakeTimeout:   tlsHandshakeTimeout,
+					ResponseHeaderTimeout: responseHeaderTimeout,
+				}),
+			}
+			if objectWriter, err = downloader.NewObjectWriter(outputURL, uploadClient); err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: invalid --output-url: %v", err))
+				return
+			}
+		}
+
+		dnsResolver = newResolver(resolverAddr)
+
 		// Process accounts
-		accountList := processInput(accounts)
+		accountList, err := mergePatterns(processInput(accounts), accountPattern)
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: invalid --account-pattern: %v", err))
+			return
+		}
 		if len(accountList) == 0 {
 			red := color.New(color.FgRed)
-			fmt.Println(red.Sprintf("No accounts provided. Use --accounts parameter."))
-			fmt.Println()
+			fmt.Fprintln(status, red.Sprintf("No accounts provided. Use --accounts parameter."))
+			fmt.Fprintln(status)
 			cmd.Help()
 			return
 		}
 
 		// Process containers
-		containerList := processInput(containers)
+		containerList, err := mergePatterns(processInput(containers), containerPattern)
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: invalid --container-pattern: %v", err))
+			return
+		}
 		if len(containerList) == 0 {
 			red := color.New(color.FgRed)
-			fmt.Println(red.Sprintf("No containers provided. Use --containers parameter."))
+			fmt.Fprintln(status, red.Sprintf("No containers provided. Use --containers parameter."))
+			return
+		}
+
+		// Process base domains
+		if adls && !cmd.Flags().Changed("baseDomain") {
+			baseDomain = dfsBaseDomain
+		}
+		baseDomains = splitList(baseDomain)
+		if len(baseDomains) == 0 {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("No base domain provided. Use --baseDomain parameter."))
+			return
+		}
+
+		// Accidental huge scans come from the product of the lists, not from any single one
+		combinations, err := scanCombinations(len(accountList), len(containerList), len(baseDomains))
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: %v, check your lists and patterns", err))
 			return
 		}
+		if combinations > warnPatternExpansion {
+			yellow := color.New(color.FgYellow)
+			fmt.Fprintln(os.Stderr, yellow.Sprintf("Warning: scanning %d combinations (%d account(s), %d container(s), %d domain(s)), check your patterns if this is unintended",
+				combinations, len(accountList), len(containerList), len(baseDomains)))
+		}
+
+		if shuffle {
+			if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			shuffleLists(seed, accountList, containerList)
+			cyan := color.New(color.FgCyan)
+			fmt.Fprintln(status, cyan.Sprintf("Shuffled scan order with --seed %d", seed))
+		}
+
+		probeNames = processInput(probe)
+		if checkOnly {
+			scanners = newScanners(baseDomains)
+		}
+
+		// Parse the download path template, naming the domain by default when scanning several
+		if !cmd.Flags().Changed("output-template") && len(baseDomains) > 1 {
+			outputTemplateText = defaultDomainOutputTemplate
+		}
+		tmpl, err := parseOutputTemplate(outputTemplateText)
+		if err != nil {
+			red := color.New(color.FgRed)
+			fmt.Fprintln(status, red.Sprintf("Error: invalid --output-template: %v", err))
+			return
+		}
+		outputTemplate = tmpl
+
+		if webhookURL != "" {
+			if hook, err = newWebhook(webhookURL, webhookTemplate); err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: invalid --webhook-template: %v", err))
+				return
+			}
+		}
 
 		// Calculate total number of checks to perform
-		totalChecks := len(accountList) * len(containerList)
+		totalChecks := len(accountList) * len(containerList) * len(baseDomains)
 		
 		cyan := color.New(color.FgCyan)
-		fmt.Println(cyan.Sprintf("Starting scan of %d account(s) × %d container(s) = %d total combinations", 
-			len(accountList), len(containerList), totalChecks))
+		if len(baseDomains) > 1 {
+			fmt.Fprintln(status, cyan.Sprintf("Starting scan of %d account(s) × %d container(s) × %d domain(s) = %d total combinations",
+				len(accountList), len(containerList), len(baseDomains), totalChecks))
+		} else {
+			fmt.Fprintln(status, cyan.Sprintf("Starting scan of %d account(s) × %d container(s) = %d total combinations", 
+				len(accountList), len(containerList), totalChecks))
+		}
+
+		// Every scan worker may hold a socket, every download a socket and a file
+		if fdLimit, ok := openFileLimit(); ok {
+			needed := uint64(maxGoroutines) + 2*uint64(maxParallelDownload) + 16
+			if needed > fdLimit {
+				yellow := color.New(color.FgYellow)
+				fmt.Fprintln(os.Stderr, yellow.Sprintf("Warning: up to %d file descriptors may be needed but the limit is %d. Lower --maxGoroutines/--maxParallelDownload or raise it with 'ulimit -n'.", needed, fdLimit))
+			}
+		}
+		downloadSem = make(chan struct{}, maxParallelDownload)
+
+		// Stop submitting new work on Ctrl+C, let in-flight checks finish and abort downloads
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop() // A second signal terminates immediately
+		}()
+
+		// After --deadline no new work is submitted, in-flight checks drain normally and downloads are aborted
+		if scanDeadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, scanDeadline)
+			defer cancel()
+		}
+
+		// Blob lists of all containers go to one sink, so an --output file isn't overwritten per container
+		if !isDownload && (listPath() != "" || listBlobs) {
+			sink, sinkPath, err = openSink(newWriter)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: %v", err))
+				return
+			}
+		}
+
+		// Load the checkpoint to skip combinations completed by a previous run
+		var cp *checkpoint
+		if checkpointPath != "" {
+			var err error
+			cp, err = openCheckpoint(checkpointPath)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: %v", err))
+				return
+			}
+			if cp.Len() > 0 {
+				fmt.Fprintln(status, cyan.Sprintf("Resuming from checkpoint: %d combination(s) already completed", cp.Len()))
+			}
+		}
 
 		// Create a main progress bar for overall progress
+		description := fmt.Sprintf("Checking %d account(s) x %d container(s)", len(accountList), len(containerList))
 		mainProgressBar = progressbar.NewOptions(totalChecks,
+			progressbar.OptionSetWriter(terminal),
 			progressbar.OptionEnableColorCodes(true),
 			progressbar.OptionSetWidth(50),
-			progressbar.OptionSetDescription(fmt.Sprintf("Checking %d account(s) x %d container(s)", len(accountList), len(containerList))),
+			progressbar.OptionSetDescription(description),
 			progressbar.OptionSetRenderBlankState(true),
 			progressbar.OptionShowCount(),
 			progressbar.OptionShowIts(),
@@ -175,6 +419,9 @@ It can list and download files from publicly accessible containers.`,
 				BarEnd:        "]",
 			}))
 
+		// Deep pagination runs in its own pool and reports on the main bar
+		pages = newPager(maxParallelPaging, description)
+
 		// Create semaphore for limiting goroutines
 		sem := make(chan struct{}, maxGoroutines)
 		var wg sync.WaitGroup
@@ -182,52 +429,169 @@ It can list and download files from publicly accessible containers.`,
 		var checkedCount int
 
 		// Check all combinations
-		for _, account := range accountList {
-			// Check if the domain exists using DNS lookup
-			if !domainExists(account + "." + baseDomain) {
-				if debug {
-					yellow := color.New(color.FgYellow)
-					BarPrintf(mainProgressBar, yellow, "[DEBUG] Domain %s.%s does not exist", account, baseDomain)
+	scan:
+		for _, domain := range baseDomains {
+			for _, account := range accountList {
+				// Check if the domain exists using DNS lookup
+				if !noDNSCheck {
+					exists, err := domainExists(account + "." + domain)
+					if err != nil {
+						// Transient resolver failure, let the HTTP request decide
+						if debug {
+							yellow := color.New(color.FgYellow)
+							BarPrintf(mainProgressBar, yellow, "[DEBUG] DNS lookup for %s.%s failed, scanning anyway: %v", account, domain, err)
+						}
+					} else if !exists {
+						if debug {
+							yellow := color.New(color.FgYellow)
+							BarPrintf(mainProgressBar, yellow, "[DEBUG] Domain %s.%s does not exist", account, domain)
+						}
+
+						// Update progress bar for skipped domains
+						countLock.Lock()
+						mainProgressBar.Add(len(containerList))
+						checkedCount += len(containerList)
+						countLock.Unlock()
+
+						continue
+					}
 				}
-				
-				// Update progress bar for skipped domains
-				countLock.Lock()
-				mainProgressBar.Add(len(containerList))
-				checkedCount += len(containerList)
-				countLock.Unlock()
-				
-				continue
-			}
 
-			for _, container := range containerList {
-				wg.Add(1)
-				sem <- struct{}{} // Acquire semaphore
-				go func(acc, cont string) {
-					defer wg.Done()
-					defer func() { 
-						<-sem 
-						
-						// Update progress bar after checking each container
+				for _, container := range containerList {
+					if cp != nil && cp.Completed(domain, account, container) {
+						countLock.Lock()
+						mainProgressBar.Add(1)
+						checkedCount++
+						countLock.Unlock()
+						continue
+					}
+
+					if breaker.isOpen(circuitHost(account, domain)) {
+						recordCircuitSkip(account, container, domain)
 						countLock.Lock()
 						mainProgressBar.Add(1)
 						checkedCount++
 						countLock.Unlock()
-					}() // Release semaphore
+						continue
+					}
 
-					checkContainer(acc, cont)
-				}(account, container)
+					select {
+					case <-ctx.Done():
+						break scan
+					case sem <- struct{}{}: // Acquire semaphore
+					}
+					wg.Add(1)
+					go func(acc, cont, dom string) {
+						defer wg.Done()
+						releaseSlot := sync.OnceFunc(func() { <-sem })
+						defer func() { 
+							releaseSlot()
+						
+							// Update progress bar after checking each container
+							countLock.Lock()
+							mainProgressBar.Add(1)
+							checkedCount++
+							countLock.Unlock()
+						}() // Release semaphore
+
+						// The circuit may have opened while this check waited for a slot
+						host := circuitHost(acc, dom)
+						if breaker.isOpen(host) {
+							recordCircuitSkip(acc, cont, dom)
+							return
+						}
+
+						var result azure.AccessResult
+						if checkOnly {
+							result = checkAccessOnly(acc, cont, dom)
+						} else {
+							result = checkContainer(ctx, acc, cont, dom, releaseSlot)
+						}
+						foundContainerLock.Lock()
+						summary.Add(result)
+						foundContainerLock.Unlock()
+						recordError(acc, cont, dom, result.ErrorCode)
+						breaker.record(host, result)
+						if cp != nil {
+							switch result.ErrorCode {
+							case "RequestFailed", "ReadFailed", "ServerError":
+								// Transient failures are retried on resume
+							case "":
+								cp.Record(dom, acc, cont, "public")
+							default:
+								outcome := result.ErrorCode
+								if len(result.Blobs) > 0 {
+									outcome = findingBlobAccessible // Blobs readable by --probe
+								}
+								cp.Record(dom, acc, cont, outcome)
+							}
+						}
+					}(account, container, domain)
+				}
 			}
 		}
 
 		wg.Wait()
-		fmt.Println() // Add a newline after progress bar
+		closeSink()
+		closeDedupManifest()
+		if hashes {
+			if err := closeHashManifest(); err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error: %v", err))
+			}
+		}
+		if hook != nil {
+			hook.Close()
+		}
+		if cp != nil {
+			cp.Close()
+		}
+		fmt.Fprintln(status) // Add a newline after progress bar
+
+		if ctx.Err() != nil {
+			yellow := color.New(color.FgYellow)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Fprintln(status, yellow.Sprintf("Deadline reached after %d of %d combinations.", checkedCount, totalChecks))
+			} else {
+				fmt.Fprintln(status, yellow.Sprintf("Scan interrupted after %d of %d combinations.", checkedCount, totalChecks))
+			}
+			if cp != nil {
+				fmt.Fprintln(status, yellow.Sprintf("Progress saved to %s, run again with the same --checkpoint to resume.", checkpointPath))
+			}
+		}
 		
 		// Sonuç mesajını göster
 		yellow := color.New(color.FgYellow)
-		if foundContainers > 0 {
-			fmt.Println(yellow.Sprintf("Scan completed. Found %d publicly accessible container(s).", foundContainers))
+		if found := summary.Public - suppressedContainers; found > 0 {
+			fmt.Fprintln(status, yellow.Sprintf("Scan completed. Found %d publicly accessible container(s).", found))
 		} else {
-			fmt.Println(yellow.Sprintf("Scan completed. No publicly accessible containers found. Use --debug for more details."))
+			fmt.Fprintln(status, yellow.Sprintf("Scan completed. No publicly accessible containers found. Use --debug for more details."))
+		}
+		if suppressedContainers > 0 {
+			fmt.Fprintln(status, yellow.Sprintf("%d more public container(s) had fewer than %d blobs and were not reported.", suppressedContainers, minBlobs))
+		}
+		if triageEnabled() {
+			if codes := errorSummary(); codes != "" {
+				fmt.Fprintln(status, yellow.Sprintf("Error codes: %s", codes))
+			}
+		}
+		if archiveSkipped > 0 {
+			fmt.Fprintln(status, yellow.Sprintf("Skipped %d Archive tier blob(s), use --include-archive to download them.", archiveSkipped))
+		}
+		if sensitiveFound > 0 {
+			red := color.New(color.FgRed, color.Bold)
+			fmt.Fprintln(status, red.Sprintf("Found %d readable well-known sensitive file(s).", sensitiveFound))
+		}
+		if foundBlobs > 0 {
+			fmt.Fprintln(status, yellow.Sprintf("Found %d directly accessible blob(s) in non-listable containers.", foundBlobs))
+		}
+		if hosts := breaker.trippedHosts(); len(hosts) > 0 {
+			fmt.Fprintln(status, yellow.Sprintf("Circuit tripped for %d account(s) after %d consecutive failures, %d container(s) skipped: %s",
+				len(hosts), circuitThreshold, breaker.skipped, strings.Join(hosts, ", ")))
+		}
+
+		if interactive {
+			browse(os.Stdin)
 		}
 	},
 }
@@ -243,16 +607,65 @@ func Execute() {
 func init() {
 	RootCmd.Flags().StringVarP(&accounts, "accounts", "a", "", "Account names (comma-separated) or path to a file containing account names")
 	RootCmd.Flags().StringVarP(&containers, "containers", "c", "", "Container names (comma-separated) or path to a file containing container names")
+	RootCmd.Flags().StringVar(&accountPattern, "account-pattern", "", "Account name patterns with numeric ranges (comma-separated, e.g. acme[01-99])")
+	RootCmd.Flags().StringVar(&containerPattern, "container-pattern", "", "Container name patterns with numeric ranges (comma-separated, e.g. backup[1-12])")
 	RootCmd.Flags().BoolVarP(&isDownload, "download", "d", false, "Download files from publicly accessible containers")
-	RootCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output path for lists or downloads")
-	RootCmd.Flags().BoolVarP(&skipSSL, "skipSSL", "s", true, "Skip SSL verification")
+	RootCmd.Flags().StringVarP(&outputPath, "output", "o", "", "Output path for lists or downloads (with --interactive: directory for marked blobs)")
+	RootCmd.Flags().StringVar(&outputURL, "output-url", "", "Upload downloads to s3://bucket/prefix or az://account/container/prefix instead of local disk (credentials from the environment)")
+	RootCmd.Flags().BoolVarP(&skipSSL, "skipSSL", "s", false, "Skip SSL verification (insecure)")
+	RootCmd.Flags().StringVar(&caCert, "ca-cert", "", "Path to a PEM CA bundle appended to the system pool")
+	RootCmd.Flags().StringVar(&clientCert, "client-cert", "", "Path to a PEM client certificate for mutual TLS")
+	RootCmd.Flags().StringVar(&clientKey, "client-key", "", "Path to the PEM private key for --client-cert")
 	RootCmd.Flags().IntVarP(&maxGoroutines, "maxGoroutines", "g", 500, "Maximum number of concurrent goroutines")
-	RootCmd.Flags().IntVarP(&maxParallelDownload, "maxParallelDownload", "p", 10, "Maximum number of parallel downloads")
+	RootCmd.Flags().IntVarP(&maxParallelDownload, "maxParallelDownload", "p", 10, "Maximum number of parallel downloads across all containers")
+	RootCmd.Flags().IntVar(&maxParallelPaging, "maxParallelPaging", 10, "Maximum number of containers following NextMarkers at once")
+	RootCmd.Flags().IntVar(&maxPages, "max-pages", 10000, "Maximum number of listing pages fetched per container (0 for no limit)")
 	RootCmd.Flags().BoolVarP(&debug, "debug", "v", false, "Enable debug output")
+	RootCmd.Flags().BoolVar(&interactive, "interactive", false, "After a --list scan, browse found containers, inspect blob metadata and download marked blobs")
 	RootCmd.Flags().BoolVarP(&listBlobs, "list", "l", false, "List all found blob URLs")
 	RootCmd.Flags().IntVarP(&limit, "limit", "L", 10, "Limit the number of results")
-	RootCmd.Flags().StringVarP(&baseDomain, "baseDomain", "b", "blob.core.windows.net", "Base domain for Azure Blob Storage")
+	RootCmd.Flags().StringVarP(&baseDomain, "baseDomain", "b", "blob.core.windows.net", "Base domain(s) for Azure Blob Storage (comma-separated for multiple clouds)")
+	RootCmd.Flags().BoolVar(&adls, "adls", false, "Target ADLS Gen2 dfs endpoints (default base domain: dfs.core.windows.net)")
 	RootCmd.Flags().BoolVarP(&totalCount, "total", "t", false, "Count total number of blobs traversing all NextMarkers")
+	RootCmd.Flags().DurationVar(&scanDeadline, "deadline", 0, "Stop submitting new checks after this duration, drain in-flight checks and abort downloads (e.g. 2h)")
+	RootCmd.Flags().DurationVar(&requestTimeout, "request-timeout", 0, "Timeout for each listing request (default: the --timeout client timeout)")
+	RootCmd.Flags().DurationVar(&clientTimeout, "timeout", 30*time.Second, "Overall timeout of each request including the body, raise it for large downloads (0 for none)")
+	RootCmd.Flags().DurationVar(&dialTimeout, "dial-timeout", utils.DefaultDialTimeout, "Timeout for opening each TCP connection")
+	RootCmd.Flags().DurationVar(&tlsHandshakeTimeout, "tls-handshake-timeout", utils.DefaultTLSHandshakeTimeout, "Timeout for each TLS handshake")
+	RootCmd.Flags().DurationVar(&responseHeaderTimeout, "response-header-timeout", utils.DefaultResponseHeaderTimeout, "Timeout for response headers after a request is sent")
+	RootCmd.Flags().BoolVar(&noDNSCheck, "no-dns-check", false, "Skip the DNS precheck and send HTTP requests for every account")
+	RootCmd.Flags().DurationVar(&dnsTimeout, "dns-timeout", 5*time.Second, "Timeout for each account DNS lookup")
+	RootCmd.Flags().StringVar(&resolverAddr, "resolver", "", "DNS server (host or host:port, IPv4 or IPv6) to use for the precheck")
+	RootCmd.Flags().IntVar(&jitter, "jitter", 0, "Sleep a random 0..N milliseconds before each list request")
+	RootCmd.Flags().IntVar(&circuitThreshold, "circuit-threshold", 0, "Skip an account's remaining containers after N consecutive connection failures or 5xx responses (0 disables)")
+	RootCmd.Flags().BoolVar(&shuffle, "shuffle", false, "Randomize the order of accounts and containers to spread requests across the keyspace")
+	RootCmd.Flags().Int64Var(&seed, "seed", 0, "Seed for --shuffle, to repeat an earlier order (default: random, printed at start)")
+	RootCmd.Flags().Float64Var(&rps, "rps", 0, "Maximum outbound requests per second across all workers (0 = unlimited)")
+	RootCmd.Flags().StringVar(&webhookURL, "webhook-url", "", "POST a JSON payload to this URL for every public container found")
+	RootCmd.Flags().StringVar(&webhookTemplate, "webhook-template", "", "Go template for the webhook body, e.g. '{\"text\": {{json .URL}}}'")
+	RootCmd.Flags().StringVar(&format, "format", "text", "Format of --list and --output blob lists ("+strings.Join(output.Formats(), ", ")+")")
+	RootCmd.Flags().BoolVar(&probeWellKnown, "probe-well-known", false, "Request well-known sensitive files (.env, web.config, id_rsa, ...) in every found container")
+	RootCmd.Flags().BoolVar(&aclCheck, "acl", false, "Read the declared public access level of found containers (extra request per container)")
+	RootCmd.Flags().IntVar(&minBlobs, "min-blobs", 0, "Only report public containers with at least N blobs")
+	RootCmd.Flags().BoolVar(&showErrors, "show-errors", false, "Print the error code of every container that isn't public and count codes in the summary")
+	RootCmd.Flags().StringVar(&errorFilterText, "error-filter", "", "Only print these error codes, comma separated (e.g. ContainerNotFound,PublicAccessNotPermitted)")
+	RootCmd.Flags().IntVar(&maxDepth, "max-depth", -1, "Only keep blobs at most N directories deep, 0 for root objects only (-1: no limit)")
+	RootCmd.Flags().StringVar(&tierFilter, "tier", "", "Only keep blobs in these access tiers, comma separated (e.g. Hot,Cool)")
+	RootCmd.Flags().BoolVar(&includeArchive, "include-archive", false, "Also try to download Archive tier blobs, which fail until rehydrated")
+	RootCmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "Don't download blobs whose file already exists with the listed size")
+	RootCmd.Flags().StringVar(&since, "since", "", "Only keep blobs modified after this time (RFC3339, e.g. 2024-01-02T15:04:05Z, or a date like 2024-01-02)")
+	RootCmd.Flags().StringVar(&namePattern, "name-regex", "", "Only process blobs whose name matches this regular expression")
+	RootCmd.Flags().BoolVar(&checkOnly, "check-only", false, "Only report container accessibility without enumerating blobs (faster)")
+	RootCmd.Flags().StringVar(&contentTypeFilter, "content-type", "", "Only process blobs whose Content-Type starts with one of these prefixes (comma-separated, e.g. application/,text/)")
+	RootCmd.Flags().StringVar(&probe, "probe", "", "Blob names (comma-separated) or file to request directly when listing is not permitted")
+	RootCmd.Flags().IntVar(&maxProbes, "max-probes", 20, "Maximum number of --probe requests per container")
+	RootCmd.Flags().StringVar(&checkpointPath, "checkpoint", "", "File recording completed combinations, used to resume an interrupted scan")
+	RootCmd.Flags().BoolVar(&compressOutput, "compress-output", false, "Gzip the blob URL list written with --output (implied by a .gz suffix)")
+	RootCmd.Flags().StringVar(&outputTemplateText, "output-template", defaultOutputTemplate, "Download path template relative to --output ({{.Account}} {{.Container}} {{.Domain}} {{.BlobName}} {{.ContentType}} {{.Ext}})")
+	RootCmd.Flags().BoolVar(&dedup, "dedup", false, "Replace duplicate downloaded files with hardlinks to the first copy")
+	RootCmd.Flags().Int64Var(&sampleBytes, "sample", 0, "Download only the first N bytes of each blob, saved with a .sample suffix (0: whole blobs)")
+	RootCmd.Flags().BoolVar(&hashes, "hashes", false, "Write a sha256sum-compatible manifest of downloaded files to the output directory")
+	RootCmd.Flags().StringVar(&hashAlgorithm, "hash-algorithm", "sha256", "Hash algorithm for --hashes (md5, sha1, sha256, sha512)")
 }
 
 // processInput processes the input (comma-separated string or file path)
@@ -269,12 +682,26 @@ func processInput(input string) []string {
 		file, err := os.Open(input)
 		if err != nil {
 			red := color.New(color.FgRed)
-			fmt.Println(red.Sprintf("Error opening file: %v", err))
+			fmt.Fprintln(status, red.Sprintf("Error opening file: %v", err))
 			return result
 		}
 		defer file.Close()
 
-		scanner := bufio.NewScanner(file)
+		// Gzip-compressed wordlists are detected by their magic bytes, whatever the file is named
+		buffered := bufio.NewReader(file)
+		var reader io.Reader = buffered
+		if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+			gz, err := gzip.NewReader(buffered)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Fprintln(status, red.Sprintf("Error opening file: %v", err))
+				return result
+			}
+			defer gz.Close()
+			reader = gz
+		}
+
+		scanner := bufio.NewScanner(reader)
 		for scanner.Scan() {
 			line := strings.TrimSpace(scanner.Text())
 			if line != "" {
@@ -284,31 +711,66 @@ func processInput(input string) []string {
 
 		if err := scanner.Err(); err != nil {
 			red := color.New(color.FgRed)
-			fmt.Println(red.Sprintf("Error reading file: %v", err))
+			fmt.Fprintln(status, red.Sprintf("Error reading file: %v", err))
 		}
 	} else {
 		// Input is a comma-separated string
-		for _, item := range strings.Split(input, ",") {
-			item = strings.TrimSpace(item)
-			if item != "" {
-				result = append(result, item)
-			}
-		}
+		result = splitList(input)
 	}
 
 	return result
 }
 
-// domainExists checks if a domain exists using DNS lookup
-func domainExists(domain string) bool {
-	_, err := net.LookupHost(domain)
-	return err == nil
+// splitList splits a comma-separated list, dropping empty items
+func splitList(input string) []string {
+	var result []string
+	for _, item := range strings.Split(input, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// containerLabel formats account/container for messages, naming the domain when scanning several
+func containerLabel(account, container, domain string) string {
+	if len(baseDomains) > 1 {
+		return fmt.Sprintf("%s.%s/%s", account, domain, container)
+	}
+	return fmt.Sprintf("%s/%s", account, container)
+}
+
+// sleepJitter sleeps a random duration up to --jitter milliseconds
+func sleepJitter() {
+	if jitter > 0 {
+		time.Sleep(time.Duration(rand.Intn(jitter+1)) * time.Millisecond)
+	}
 }
 
-// checkContainer checks if a container is publicly accessible
-func checkContainer(account, container string) {
-	baseURL := fmt.Sprintf("https://%s.%s/%s", account, baseDomain, container)
-	listURL := fmt.Sprintf("%s?restype=container&comp=list", baseURL)
+// shuffleLists randomizes the order of each list in place, the same way for the same seed
+func shuffleLists(seed int64, lists ...[]string) {
+	r := rand.New(rand.NewSource(seed))
+	for _, list := range lists {
+		r.Shuffle(len(list), func(i, j int) {
+			list[i], list[j] = list[j], list[i]
+		})
+	}
+}
+
+// checkContainer checks if a container is publicly accessible.
+// releaseSlot, if set, is called before deep pagination to free the caller's scan slot.
+// Cancelling ctx aborts downloads, the container check itself always completes.
+func checkContainer(ctx context.Context, account, container, domain string, releaseSlot func()) (result azure.AccessResult) {
+	target := containerLabel(account, container, domain)
+	baseURL := fmt.Sprintf("https://%s.%s/%s", account, domain, container)
+	listURL := listRequestURL(account, container, domain, "")
+
+	result = azure.AccessResult{
+		Account:   account,
+		Container: container,
+		URL:       baseURL,
+	}
 
 	if debug {
 		cyan := color.New(color.FgCyan)
@@ -316,12 +778,14 @@ func checkContainer(account, container string) {
 	}
 
 	// Send HTTP request
-	resp, err := client.Get(listURL)
+	sleepJitter()
+	resp, err := httpGet(listURL)
 	if err != nil {
 		if debug {
 			red := color.New(color.FgRed)
 			BarPrintf(mainProgressBar, red, "[DEBUG] Error: %v", err)
 		}
+		result.ErrorCode = "RequestFailed"
 		return
 	}
 	defer resp.Body.Close()
@@ -333,427 +797,254 @@ func checkContainer(account, container string) {
 			red := color.New(color.FgRed)
 			BarPrintf(mainProgressBar, red, "[DEBUG] Error reading response: %v", err)
 		}
+		result.ErrorCode = "ReadFailed"
 		return
 	}
 
-	// Parse XML response
-	var blobError BlobError
-	if err := xml.Unmarshal(body, &blobError); err == nil && blobError.Code != "" {
+	// Parse error response
+	if blobError, ok := azure.ParseError(body); ok {
+		result.ErrorCode = blobError.Code
 		switch blobError.Code {
 		case "NoAuthenticationInformation":
 			if debug {
 				yellow := color.New(color.FgYellow)
-				BarPrintf(mainProgressBar, yellow, "[DEBUG] %s/%s: No authentication information", account, container)
+				BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: No authentication information", target)
 			}
 			return
 		case "PublicAccessNotPermitted":
 			yellow := color.New(color.FgYellow)
-			BarPrintf(mainProgressBar, yellow, "[INFO] %s/%s: Public access not permitted", account, container)
+			BarPrintf(mainProgressBar, yellow, "[INFO] %s: Public access not permitted", target)
+			if len(probeNames) > 0 {
+				result.Blobs = probeBlobs(account, container, domain, probeNames)
+				reportProbedBlobs(result, domain)
+			}
 			return
 		case "ResourceNotFound":
 			// Resource not found, might be accessible
-			break
+			result.ErrorCode = ""
 		default:
 			if debug {
 				yellow := color.New(color.FgYellow)
-				BarPrintf(mainProgressBar, yellow, "[DEBUG] %s/%s: %s - %s", account, container, blobError.Code, blobError.Message)
+				BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: %s - %s", target, blobError.Code, blobError.Message)
 			}
 			return
 		}
 	}
 
-	// Parse as EnumerationResults
-	var results EnumerationResults
-	if err := xml.Unmarshal(body, &results); err != nil || len(results.Blobs.Blob) == 0 {
+	// Server errors without an Azure error body, e.g. a 503 from a proxy, aren't worth parsing
+	if resp.StatusCode >= http.StatusInternalServerError {
 		if debug {
 			yellow := color.New(color.FgYellow)
-			BarPrintf(mainProgressBar, yellow, "[DEBUG] %s/%s: Not accessible or no blobs found", account, container)
+			BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: HTTP %d", target, resp.StatusCode)
 		}
+		result.ErrorCode = "ServerError"
+		return
+	}
+
+	// Parse the listing, retrying when the body is malformed (e.g. truncated by a proxy)
+	results, err := parseListing(resp, body)
+	for attempt := 1; err != nil && attempt <= listRetries; attempt++ {
+		if debug {
+			yellow := color.New(color.FgYellow)
+			BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: Malformed listing (%d bytes): %v, retrying (%d/%d)", target, len(body), err, attempt, listRetries)
+		}
+		sleepJitter()
+		var retryResp *http.Response
+		if retryResp, body, err = fetchListing(listURL); err == nil {
+			results, err = parseListing(retryResp, body)
+		}
+	}
+	if err != nil || len(results.Blobs) == 0 {
+		if debug {
+			yellow := color.New(color.FgYellow)
+			BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: Not accessible or no blobs found", target)
+		}
+		result.ErrorCode = "NotAccessible"
 		return
 	}
 
 	// Container is accessible and has blobs
-	if totalCount && results.NextMarker != "" {
-		// Başlangıçtaki blob sayısını alıyoruz
-		totalBlobCount := len(results.Blobs.Blob)
-		nextMarker := results.NextMarker
-		
-		// Total 10000 ile başlayalım çünkü toplam sayıyı bilmiyoruz
-		countBar := progressbar.NewOptions(10000,
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionSetWidth(50),
-			progressbar.OptionSetDescription(fmt.Sprintf("Counting blobs in %s/%s", account, container)),
-			progressbar.OptionSetRenderBlankState(true),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionClearOnFinish(),
-			progressbar.OptionOnCompletion(func() { fmt.Println() }),
-			progressbar.OptionSetTheme(progressbar.Theme{
-				Saucer:        "[cyan]=[reset]",
-				SaucerHead:    "[cyan]>[reset]",
-				SaucerPadding: " ",
-				BarStart:      "[",
-				BarEnd:        "]",
-			}))
-		
-		// İlk sayfadaki blob sayısını progress bar'a ekleyelim
-		countBar.Add(totalBlobCount)
-		
-		// NextMarker ile tüm blob'ları sayıyoruz
-		for nextMarker != "" {
-			nextURL := fmt.Sprintf("%s&marker=%s", listURL, url.QueryEscape(nextMarker))
-			
-			if debug {
-				cyan := color.New(color.FgCyan)
-				BarPrintf(countBar, cyan, "[DEBUG] Counting blobs with next marker: %s", nextURL)
-			}
-			
-			resp, err := client.Get(nextURL)
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(countBar, red, "[DEBUG] Error fetching next marker for count: %v", err)
-				}
-				break
-			}
-			
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(countBar, red, "[DEBUG] Error reading next marker response for count: %v", err)
-				}
-				break
-			}
-			
-			var nextResults EnumerationResults
-			if err := xml.Unmarshal(body, &nextResults); err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(countBar, red, "[DEBUG] Error parsing next marker response for count: %v", err)
-				}
-				break
-			}
-			
-			blobCount := len(nextResults.Blobs.Blob)
-			totalBlobCount += blobCount
-			countBar.Add(blobCount)
-			
-			// Progress bar'ın maksimum değerini gerekirse güncelle
-			if totalBlobCount > 9000 {
-				countBar.ChangeMax(totalBlobCount + 5000)
-			}
-			
-			nextMarker = nextResults.NextMarker
-			
-			if debug {
-				cyan := color.New(color.FgCyan)
-				BarPrintf(countBar, cyan, "[DEBUG] Total blobs counted so far: %d", totalBlobCount)
-			}
+	result.IsPublic = true
+	allBlobs := results.Blobs
+	pagination := newCursor(results.NextMarker)
+
+	// deepList pages through the rest of the container in the pagination pool, freeing this
+	// worker's scan slot for other checks while it does. It returns the number of blobs listed.
+	deepList := func(keep int, countAll bool) int {
+		if releaseSlot != nil {
+			releaseSlot()
+		}
+		more, count := pages.pageThrough(account, container, domain, pagination, keep, countAll)
+		allBlobs = append(allBlobs, more...)
+		return count
+	}
+
+	listed := len(allBlobs)
+	if totalCount && pagination.marker != "" {
+		// Count every blob, keeping the ones needed for the limit on the way
+		listed += deepList(limit-len(allBlobs), true)
+	} else if listed < minBlobs && pagination.marker != "" {
+		// Follow markers only as far as needed to reach --min-blobs
+		listed += deepList(minBlobs-listed, false)
+	}
+	result.BlobCount = listed
+
+	// Suppress near-empty containers, but keep them in the tally
+	if listed < minBlobs {
+		if debug {
+			yellow := color.New(color.FgYellow)
+			BarPrintf(mainProgressBar, yellow, "[DEBUG] %s: Public with %d blobs, below --min-blobs %d", target, listed, minBlobs)
 		}
-		
-		// Progress bar'ı bozmadan renkli mesajımızı gösterelim
-		green := color.New(color.FgGreen)
-		BarPrintf(countBar, green, "[FOUND] %s/%s is publicly accessible with %d blobs (total)", account, container, totalBlobCount)
-		
-		// Erişilebilir container sayacını artır
 		foundContainerLock.Lock()
-		foundContainers++
+		suppressedContainers++
 		foundContainerLock.Unlock()
-	} else if len(results.Blobs.Blob) >= 5000 {
+		return
+	}
+
+	if aclCheck {
+		result.AccessLevel = containerAccessLevel(account, container, domain)
+	}
+	level := accessLevelSuffix(result.AccessLevel)
+
+	if totalCount {
+		green := color.New(color.FgGreen)
+		BarPrintf(mainProgressBar, green, "[FOUND] %s is publicly accessible with %d blobs (total)%s", target, listed, level)
+	} else if len(results.Blobs) >= 5000 {
 		// Ana ilerleme çubuğu üzerinde renkli mesajımızı gösterelim
 		green := color.New(color.FgGreen)
-		BarPrintf(mainProgressBar, green, "[FOUND] %s/%s is publicly accessible with more than 5000 blobs", account, container)
-		
-		// Erişilebilir container sayacını artır
-		foundContainerLock.Lock()
-		foundContainers++
-		foundContainerLock.Unlock()
+		BarPrintf(mainProgressBar, green, "[FOUND] %s is publicly accessible with more than 5000 blobs%s", target, level)
 	} else {
 		// Ana ilerleme çubuğu üzerinde renkli mesajımızı gösterelim
 		green := color.New(color.FgGreen)
-		BarPrintf(mainProgressBar, green, "[FOUND] %s/%s is publicly accessible with %d blobs", account, container, len(results.Blobs.Blob))
-		
-		// Erişilebilir container sayacını artır
-		foundContainerLock.Lock()
-		foundContainers++
-		foundContainerLock.Unlock()
+		BarPrintf(mainProgressBar, green, "[FOUND] %s is publicly accessible with %d blobs%s", target, listed, level)
+	}
+
+	if probeWellKnown {
+		result.SensitiveFiles = probeSensitiveFiles(account, container, domain)
+	}
+	if hook != nil {
+		hook.Notify(result, domain)
 	}
 
 	// If limit is greater than 5000 and NextMarker is present, get additional blobs
-	allBlobs := results.Blobs.Blob
-	nextMarker := results.NextMarker
+	if limit > 5000 && pagination.marker != "" {
+		deepList(limit-len(allBlobs), false)
+	}
 
-	if limit > 5000 && nextMarker != "" {
-		// Progress bar oluştur
-		listBar := progressbar.NewOptions(limit,
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionSetWidth(50),
-			progressbar.OptionSetDescription(fmt.Sprintf("Fetching blobs from %s/%s", account, container)),
-			progressbar.OptionSetRenderBlankState(true),
-			progressbar.OptionShowCount(),
-			progressbar.OptionShowIts(),
-			progressbar.OptionClearOnFinish(),
-			progressbar.OptionOnCompletion(func() { fmt.Println() }),
-			progressbar.OptionSetTheme(progressbar.Theme{
-				Saucer:        "[blue]=[reset]",
-				SaucerHead:    "[blue]>[reset]",
-				SaucerPadding: " ",
-				BarStart:      "[",
-				BarEnd:        "]",
-			}))
-		
-		// İlk sayfadaki blob sayısını progress bar'a ekle
-		listBar.Add(len(allBlobs))
-		
-		for nextMarker != "" && len(allBlobs) < limit {
-			nextURL := fmt.Sprintf("%s&marker=%s", listURL, url.QueryEscape(nextMarker))
-			
-			if debug {
-				cyan := color.New(color.FgCyan)
-				BarPrintf(listBar, cyan, "[DEBUG] Fetching next marker: %s", nextURL)
-			}
-			
-			resp, err := client.Get(nextURL)
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(listBar, red, "[DEBUG] Error fetching next marker: %v", err)
-				}
-				break
-			}
-			
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(listBar, red, "[DEBUG] Error reading next marker response: %v", err)
-				}
-				break
-			}
-			
-			var nextResults EnumerationResults
-			if err := xml.Unmarshal(body, &nextResults); err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(listBar, red, "[DEBUG] Error parsing next marker response: %v", err)
-				}
-				break
-			}
-			
-			blobCount := len(nextResults.Blobs.Blob)
-			allBlobs = append(allBlobs, nextResults.Blobs.Blob...)
-			listBar.Add(blobCount)
-			
-			nextMarker = nextResults.NextMarker
-			
-			if debug {
-				cyan := color.New(color.FgCyan)
-				BarPrintf(listBar, cyan, "[DEBUG] Total blobs found so far: %d", len(allBlobs))
-			}
-		}
-		
-		fmt.Println() // Add a newline after progress bar
+	listedBlobs := len(allBlobs)
+	allBlobs = filterBlobs(allBlobs)
+	if debug && len(allBlobs) < listedBlobs {
+		cyan := color.New(color.FgCyan)
+		BarPrintf(mainProgressBar, cyan, "[DEBUG] %s: %d of %d listed blobs filtered out", target, listedBlobs-len(allBlobs), listedBlobs)
 	}
-	
+
 	// Limit the number of blobs if necessary
 	if limit > 0 && len(allBlobs) > limit {
 		allBlobs = allBlobs[:limit]
 	}
 
 	// Process blobs according to the requested action
-	 if isDownload {
-		downloadBlobs(account, container, allBlobs)
-	} else if outputPath != "" {
-		saveBlobList(account, container, allBlobs)
-	} else if listBlobs {
-		listBlobURLs(account, container, allBlobs)
-	} else {
-		// Just print the count, already done above
-	}
-}
-
-// listBlobURLs prints URLs of blobs to console
-func listBlobURLs(account, container string, blobs []Blob) {
-	// Progress bar oluştur
-	listURLBar := progressbar.NewOptions(len(blobs),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionSetWidth(50),
-		progressbar.OptionSetDescription(fmt.Sprintf("Listing %d URLs from %s/%s", len(blobs), account, container)),
-		progressbar.OptionSetRenderBlankState(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionShowIts(),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionOnCompletion(func() { fmt.Println() }),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[blue]=[reset]",
-			SaucerHead:    "[blue]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}))
-	
-	blue := color.New(color.FgBlue)
-	for _, blob := range blobs {
-		blobURL := fmt.Sprintf("https://%s.%s/%s/%s", account, baseDomain, container, blob.Name)
-		BarPrintf(listURLBar, blue, "%s", blobURL)
-		listURLBar.Add(1)
+	if isDownload {
+		downloadBlobs(ctx, account, container, domain, allBlobs)
+	} else if sink != nil {
+		writeBlobs(account, container, domain, allBlobs)
+		writeSensitiveFiles(account, container, domain, result.SensitiveFiles)
 	}
-}
-
-// saveBlobList saves the list of blob URLs to a file
-func saveBlobList(account, container string, blobs []Blob) {
-	outputFile := outputPath
-	if outputFile == "" {
-		outputFile = fmt.Sprintf("%s_%s_blobs.txt", account, container)
+	if interactive {
+		rememberContainer(result, domain, allBlobs)
 	}
 
-	file, err := os.Create(outputFile)
-	if err != nil {
-		red := color.New(color.FgRed)
-		fmt.Println(red.Sprintf("Error creating output file: %v", err))
-		return
-	}
-	defer file.Close()
+	return
+}
 
-	// When writing to a file, assume the limit should be large (99999)
-	saveLimit := 99999
-	if limit > saveLimit {
-		saveLimit = limit
+// downloadBlobs downloads all blobs from a container until ctx is cancelled
+func downloadBlobs(ctx context.Context, account, container, domain string, blobs []azure.Blob) {
+	// Archive tier blobs can't be read until they are rehydrated, so downloading them only fails
+	archived := 0
+	if !includeArchive {
+		readable := make([]azure.Blob, 0, len(blobs))
+		for _, blob := range blobs {
+			if !isArchived(blob) {
+				readable = append(readable, blob)
+			}
+		}
+		archived = len(blobs) - len(readable)
+		blobs = readable
 	}
 
-	// Limit the number of blobs if necessary
-	blobsToSave := blobs
-	if len(blobsToSave) > saveLimit {
-		blobsToSave = blobsToSave[:saveLimit]
-	}
-
-	// Progress bar oluştur
-	saveBar := progressbar.NewOptions(len(blobsToSave),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionSetWidth(50),
-		progressbar.OptionSetDescription(fmt.Sprintf("Saving %d URLs to %s", len(blobsToSave), outputFile)),
-		progressbar.OptionSetRenderBlankState(true),
-		progressbar.OptionShowCount(),
-		progressbar.OptionShowIts(),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionOnCompletion(func() { fmt.Println() }),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}))
-
-	for _, blob := range blobsToSave {
-		blobURL := fmt.Sprintf("https://%s.%s/%s/%s", account, baseDomain, container, blob.Name)
-		fmt.Fprintln(file, blobURL)
-		saveBar.Add(1)
+	// Create a byte progress bar sized from the listed content lengths
+	var totalBytes int64
+	for _, blob := range blobs {
+		totalBytes += downloader.ExpectedBytes(blob, sampleBytes)
 	}
+	progress := utils.NewProgressBar(0,
+		utils.WithWriter(terminal),
+		utils.WithBytes(totalBytes),
+		utils.WithDescription(fmt.Sprintf("Downloading %d files from %s", len(blobs), containerLabel(account, container, domain))))
+	bar := progress.Bar()
 
-	// Progress bar'ı bozmadan renkli mesajımızı gösterelim
-	green := color.New(color.FgGreen)
-	BarPrintf(saveBar, green, "Saved %d blob URLs to %s", len(blobsToSave), outputFile)
-}
-
-// downloadBlobs downloads all blobs from a container
-func downloadBlobs(account, container string, blobs []Blob) {
-	// Create output directory
-	outputDir := filepath.Join(outputPath, account, container)
-	
-
-	err := os.MkdirAll(outputDir, 0755)
-	if err != nil {
-		red := color.New(color.FgRed)
-		fmt.Println(red.Sprintf("Error creating output directory: %v", err))
-		return
+	if archived > 0 {
+		yellow := color.New(color.FgYellow)
+		BarPrintf(bar, yellow, "[INFO] %s: Skipping %d Archive tier blob(s), use --include-archive to try them", containerLabel(account, container, domain), archived)
+		foundContainerLock.Lock()
+		archiveSkipped += archived
+		foundContainerLock.Unlock()
 	}
 
-	// Create progress bar
-	bar := progressbar.NewOptions(len(blobs),
-		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowBytes(true),
-		progressbar.OptionFullWidth(),
-		progressbar.OptionSetDescription(fmt.Sprintf("Downloading %d files", len(blobs))),
-		progressbar.OptionSetRenderBlankState(true),
-		progressbar.OptionClearOnFinish(),
-		progressbar.OptionOnCompletion(func() { fmt.Println() }),
-		progressbar.OptionSetTheme(progressbar.Theme{
-			Saucer:        "[green]=[reset]",
-			SaucerHead:    "[green]>[reset]",
-			SaucerPadding: " ",
-			BarStart:      "[",
-			BarEnd:        "]",
-		}))
-
-	// Create semaphore for limiting parallel downloads
-	sem := make(chan struct{}, maxParallelDownload)
-	var wg sync.WaitGroup
-
-	for i, blob := range blobs {
-		wg.Add(1)
-		sem <- struct{}{} // Acquire semaphore
-		go func(i int, blob Blob) {
-			defer wg.Done()
-			defer func() { <-sem }() // Release semaphore
-
-			blobURL := fmt.Sprintf("https://%s.%s/%s/%s", account, baseDomain, container, blob.Name)
-			
-			// Create directories for the blob path if needed
-			filename := filepath.Join(outputDir, blob.Name)
-			err := os.MkdirAll(filepath.Dir(filename), 0755)
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(bar, red, "[DEBUG] Error creating directory for %s: %v", filename, err)
-				}
-				bar.Add(1)
-				return
+	opts := downloader.Options{
+		Account:      account,
+		Container:    container,
+		BaseDomain:   domain,
+		OutputDir:    outputPath,
+		Writer:       objectWriter,
+		Semaphore:    downloadSem,
+		SkipExisting: skipExisting,
+		Path: func(blob azure.Blob) (string, error) {
+			return blobPath(account, container, domain, blob)
+		},
+		Progress: func(fr downloader.FileResult) {
+			// Account for bytes that were never streamed so the bar still completes
+			if fr.Err != nil || fr.Skipped {
+				progress.AddBytes(downloader.ExpectedBytes(fr.Blob, sampleBytes) - fr.Bytes)
 			}
 
-			// Download the blob
-			resp, err := client.Get(blobURL)
-			if err != nil {
+			if fr.Err != nil {
 				if debug {
 					red := color.New(color.FgRed)
-					BarPrintf(bar, red, "[DEBUG] Error downloading %s: %v", blobURL, err)
+					BarPrintf(bar, red, "[DEBUG] Error downloading %s: %v", fr.URL, fr.Err)
 				}
-				bar.Add(1)
 				return
 			}
-			defer resp.Body.Close()
-
-			// Create the file
-			out, err := os.Create(filename)
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(bar, red, "[DEBUG] Error creating file %s: %v", filename, err)
-				}
-				bar.Add(1)
-				return
+			if dedup && fr.Sums != nil {
+				dedupFile(bar, fr.Path, fr.Sums["sha256"])
 			}
-			defer out.Close()
-
-			// Write to file and update progress
-			_, err = io.Copy(out, resp.Body)
-			if err != nil {
-				if debug {
-					red := color.New(color.FgRed)
-					BarPrintf(bar, red, "[DEBUG] Error writing to file %s: %v", filename, err)
-				}
+			if hashes {
+				recordHash(fr.Path, fr.Sums[hashAlgorithm])
 			}
+		},
+		BytesWriter:     progress,
+		IfModifiedSince: sinceTime,
+		SampleBytes:     sampleBytes,
+	}
+	if dedup || hashes {
+		opts.Hashes = make(map[string]func() hash.Hash)
+	}
+	if dedup {
+		opts.Hashes["sha256"] = sha256.New
+	}
+	if hashes {
+		opts.Hashes[hashAlgorithm] = hashAlgorithms[hashAlgorithm]
+	}
 
-			bar.Add(1)
-		}(i, blob)
+	result := downloader.DownloadContainer(ctx, client, blobs, opts)
+	if ctx.Err() != nil {
+		yellow := color.New(color.FgYellow)
+		BarPrintf(bar, yellow, "[INFO] %s: Download stopped, %d of %d files not downloaded", containerLabel(account, container, domain), len(blobs)-result.Succeeded-result.Skipped, len(blobs))
+		return
 	}
 
-	wg.Wait()
-	
 	// Progress bar'ı bozmadan renkli mesajımızı gösterelim
 	green := color.New(color.FgGreen)
-	BarPrintf(bar, green, "Downloaded %d files to %s", len(blobs), outputDir)
-} 
\ No newline at end of file
+	BarPrintf(bar, green, "Downloaded %d files from %s (%d skipped, %d failed)", result.Succeeded, containerLabel(account, container, domain), result.Skipped, result.Failed)
+}
+
@@ -0,0 +1,211 @@
+package blobber
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+)
+
+// testDomain is the base domain the fake storage server answers for
+const testDomain = "blob.core.windows.net"
+
+// fakeStorage starts a TLS server standing in for every account of testDomain and points
+// the global client at it. Printed messages are discarded. It returns the request count.
+func fakeStorage(t *testing.T, handler http.HandlerFunc) *requestCounter {
+	t.Helper()
+	counter := &requestCounter{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter.add()
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	addr := srv.Listener.Addr().String()
+	oldClient, oldTerminal, oldBar := client, terminal, mainProgressBar
+	client = &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	terminal = io.Discard
+	mainProgressBar = progressbar.NewOptions(-1, progressbar.OptionSetWriter(io.Discard))
+	t.Cleanup(func() {
+		client, terminal, mainProgressBar = oldClient, oldTerminal, oldBar
+	})
+	return counter
+}
+
+// requestCounter counts requests served by fakeStorage
+type requestCounter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *requestCounter) add() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func (c *requestCounter) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// listing is a one-blob container listing
+const listing = `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="https://acme.blob.core.windows.net/" ContainerName="public"><Blobs><Blob><Name>a.txt</Name><Properties><Content-Length>5</Content-Length></Properties></Blob></Blobs><NextMarker /></EnumerationResults>`
+
+func TestCheckContainerRetriesMalformedListing(t *testing.T) {
+	served := 0
+	requests := fakeStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		served++
+		if served == 1 {
+			io.WriteString(w, listing[:len(listing)/2]) // Truncated by a proxy
+			return
+		}
+		io.WriteString(w, listing)
+	})
+
+	result := checkContainer(context.Background(), "acme", "public", testDomain, nil)
+	if !result.IsPublic || result.ErrorCode != "" {
+		t.Fatalf("result = %+v, want a public container", result)
+	}
+	if result.BlobCount != 1 {
+		t.Errorf("BlobCount = %d, want 1", result.BlobCount)
+	}
+	if got := requests.count(); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
+
+func TestCheckContainerGivesUpOnMalformedListing(t *testing.T) {
+	requests := fakeStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, listing[:len(listing)/2])
+	})
+
+	result := checkContainer(context.Background(), "acme", "public", testDomain, nil)
+	if result.IsPublic || result.ErrorCode != "NotAccessible" {
+		t.Errorf("result = %+v, want NotAccessible", result)
+	}
+	if got, want := requests.count(), 1+listRetries; got != want {
+		t.Errorf("got %d requests, want %d", got, want)
+	}
+}
+
+// setDownload turns on --download into a temporary --output directory for one test
+func setDownload(t *testing.T) string {
+	t.Helper()
+	tmpl, err := parseOutputTemplate(defaultOutputTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	oldDownload, oldOutput, oldTemplate := isDownload, outputPath, outputTemplate
+	isDownload, outputPath, outputTemplate = true, dir, tmpl
+	t.Cleanup(func() {
+		isDownload, outputPath, outputTemplate = oldDownload, oldOutput, oldTemplate
+	})
+	return dir
+}
+
+func TestCheckContainerStopsDownloadsOnCancel(t *testing.T) {
+	dir := setDownload(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var blobRequests requestCounter
+	fakeStorage(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("comp") == "list" {
+			io.WriteString(w, listing)
+			return
+		}
+		// The deadline passes while the blob is being served
+		blobRequests.add()
+		cancel()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+			io.WriteString(w, "hello")
+		}
+	})
+
+	result := checkContainer(ctx, "acme", "public", testDomain, nil)
+	if !result.IsPublic {
+		t.Fatalf("result = %+v, want a public container", result)
+	}
+	if got := blobRequests.count(); got != 1 {
+		t.Errorf("got %d blob requests, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acme", "public", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("a.txt was written after cancelling (stat error %v)", err)
+	}
+
+	// A scan already past its deadline lists the container but downloads nothing
+	checkContainer(ctx, "acme", "public", testDomain, nil)
+	if got := blobRequests.count(); got != 1 {
+		t.Errorf("got %d blob requests after the deadline, want 1", got)
+	}
+}
+
+// writeWordlist writes lines to a file in dir, gzip-compressed if compress is set
+func writeWordlist(t *testing.T, dir, name string, compress bool, lines ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcessInput(t *testing.T) {
+	dir := t.TempDir()
+	words := []string{"acme", "  contoso  ", "", "fabrikam"}
+	want := []string{"acme", "contoso", "fabrikam"}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"plain file", writeWordlist(t, dir, "accounts.txt", false, words...)},
+		{"gzip file", writeWordlist(t, dir, "accounts.txt.gz", true, words...)},
+		{"gzip without .gz name", writeWordlist(t, dir, "accounts.lst", true, words...)},
+		{"comma-separated list", "acme, contoso,,fabrikam"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processInput(tt.input); !reflect.DeepEqual(got, want) {
+				t.Errorf("processInput() = %q, want %q", got, want)
+			}
+		})
+	}
+}
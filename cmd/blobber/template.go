@@ -0,0 +1,50 @@
+package blobber
+
+import (
+	"path"
+	"strings"
+	"text/template"
+
+	"blobber/pkg/azure"
+)
+
+// Default download layouts, relative to --output
+const (
+	defaultOutputTemplate       = "{{.Account}}/{{.Container}}/{{.BlobName}}"
+	defaultDomainOutputTemplate = "{{.Domain}}/{{.Account}}/{{.Container}}/{{.BlobName}}"
+)
+
+// outputTemplate computes download destinations, parsed from --output-template
+var outputTemplate *template.Template
+
+// outputTemplateData holds the variables available to --output-template
+type outputTemplateData struct {
+	Account     string
+	Container   string
+	Domain      string
+	BlobName    string
+	ContentType string
+	Ext         string
+}
+
+// parseOutputTemplate parses a download path template.
+// The "flat" function replaces slashes, e.g. {{flat .BlobName}} turns "a/b.txt" into "a-b.txt".
+func parseOutputTemplate(text string) (*template.Template, error) {
+	return template.New("output").Option("missingkey=error").Funcs(template.FuncMap{
+		"flat": func(s string) string { return strings.ReplaceAll(s, "/", "-") },
+	}).Parse(text)
+}
+
+// blobPath evaluates the output template for a blob, returning a path relative to --output
+func blobPath(account, container, domain string, blob azure.Blob) (string, error) {
+	var sb strings.Builder
+	err := outputTemplate.Execute(&sb, outputTemplateData{
+		Account:     account,
+		Container:   container,
+		Domain:      domain,
+		BlobName:    blob.Name,
+		ContentType: blob.Properties.ContentType,
+		Ext:         path.Ext(blob.Name),
+	})
+	return sb.String(), err
+}
@@ -0,0 +1,59 @@
+package blobber
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+)
+
+// outputLock serializes every write to the terminal: progress bar renders, BarPrintf lines,
+// status messages and console output of the sink. Without it, bars redrawing from different
+// goroutines tear colored lines apart.
+var outputLock sync.Mutex
+
+// terminal is the writer all progress bars and printed lines go through
+var terminal io.Writer = lockedWriter{os.Stdout}
+
+// status receives messages printed outside the progress bar, such as errors and the summary
+var status io.Writer = lockedWriter{os.Stdout}
+
+// statusToStderr moves progress bars and messages to stderr. Colors follow stderr, since
+// the color package disables them whenever stdout isn't a terminal.
+func statusToStderr() {
+	terminal = lockedWriter{os.Stderr}
+	status = lockedWriter{os.Stderr}
+	color.NoColor = os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !isTerminal(os.Stderr)
+}
+
+// isTerminal reports whether f is a terminal rather than a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// lockedWriter makes each Write atomic under outputLock
+type lockedWriter struct {
+	w io.Writer
+}
+
+func (l lockedWriter) Write(p []byte) (int, error) {
+	outputLock.Lock()
+	defer outputLock.Unlock()
+	return l.w.Write(p)
+}
+
+// clearLine moves to the start of the line and erases the bar drawn there
+const clearLine = "\r\033[K"
+
+// printAbove prints text, which must end with a newline, in place of the bar line in a single
+// write and then redraws bar below it. Unlike progressbar.Bprintf nothing is buffered, so
+// lines printed just before a bar finishes aren't lost.
+func printAbove(bar *progressbar.ProgressBar, text string) {
+	terminal.Write([]byte(clearLine + text))
+	if bar != nil && !bar.IsFinished() {
+		bar.Add(0)
+	}
+}
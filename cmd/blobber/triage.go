@@ -0,0 +1,45 @@
+package blobber
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	showErrors      bool
+	errorFilterText string
+	errorFilter     []string // Error codes shown by --error-filter, all codes when empty
+)
+
+// triageEnabled reports whether per-container error codes are shown and summarized
+func triageEnabled() bool {
+	return showErrors || len(errorFilter) > 0
+}
+
+// recordError prints the error code of a checked container when it passes --error-filter
+func recordError(account, container, domain, code string) {
+	if !triageEnabled() || code == "" {
+		return
+	}
+
+	if len(errorFilter) == 0 || slices.Contains(errorFilter, code) {
+		red := color.New(color.FgRed)
+		BarPrintf(mainProgressBar, red, "[ERROR] %s: %s", containerLabel(account, container, domain), code)
+	}
+}
+
+// errorSummary formats the error code counts of the scan, most frequent first
+func errorSummary() string {
+	foundContainerLock.Lock()
+	defer foundContainerLock.Unlock()
+
+	codes := summary.SortedErrorCodes()
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s: %d", code, summary.ErrorCodes[code]))
+	}
+	return strings.Join(parts, ", ")
+}
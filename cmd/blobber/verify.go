@@ -0,0 +1,139 @@
+package blobber
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"blobber/pkg/downloader"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// verifyManifest is an explicit manifest for the verify command, found in the directory otherwise
+var verifyManifest string
+
+// verifyCmd re-checks downloaded files against the manifest written by --hashes
+var verifyCmd = &cobra.Command{
+	Use:   "verify [output-dir]",
+	Short: "Verify downloaded files against their --hashes manifest",
+	Long: `Verify re-hashes the files of a previous download and compares them with the
+SHA256SUMS-style manifest written by --hashes, reporting missing or corrupted files.
+It exits with a non-zero status if any file fails.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) == 1 {
+			dir = args[0]
+		} else if verifyManifest != "" {
+			dir = filepath.Dir(verifyManifest)
+		}
+
+		manifests := []string{verifyManifest}
+		if verifyManifest == "" {
+			manifests = findHashManifests(dir)
+		}
+		if len(manifests) == 0 {
+			red := color.New(color.FgRed)
+			fmt.Println(red.Sprintf("No hash manifest found in %s. Download with --hashes to create one.", dir))
+			os.Exit(1)
+		}
+
+		checked, failed := 0, 0
+		for _, manifest := range manifests {
+			c, f, err := verifyHashManifest(dir, manifest)
+			if err != nil {
+				red := color.New(color.FgRed)
+				fmt.Println(red.Sprintf("Error: %v", err))
+				os.Exit(1)
+			}
+			checked += c
+			failed += f
+		}
+
+		if failed > 0 {
+			red := color.New(color.FgRed)
+			fmt.Println(red.Sprintf("%d of %d file(s) failed verification.", failed, checked))
+			os.Exit(1)
+		}
+		green := color.New(color.FgGreen)
+		fmt.Println(green.Sprintf("Verified %d file(s), all OK.", checked))
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyManifest, "manifest", "", "Manifest to verify, e.g. out/SHA256SUMS (default: every *SUMS manifest in the directory)")
+	RootCmd.AddCommand(verifyCmd)
+}
+
+// findHashManifests returns the manifests --hashes may have written to dir
+func findHashManifests(dir string) []string {
+	var manifests []string
+	for algorithm := range hashAlgorithms {
+		path := filepath.Join(dir, strings.ToUpper(algorithm)+"SUMS")
+		if _, err := os.Stat(path); err == nil {
+			manifests = append(manifests, path)
+		}
+	}
+	return manifests
+}
+
+// verifyHashManifest re-hashes every file listed in a manifest, relative to dir, and prints
+// the ones that are missing or don't match. The algorithm comes from the manifest name.
+func verifyHashManifest(dir, manifest string) (checked, failed int, err error) {
+	algorithm := strings.ToLower(strings.TrimSuffix(filepath.Base(manifest), "SUMS"))
+	newHash, ok := hashAlgorithms[algorithm]
+	if !ok {
+		return 0, 0, fmt.Errorf("can't tell the hash algorithm of %s, expected a name like SHA256SUMS", manifest)
+	}
+	constructors := map[string]func() hash.Hash{algorithm: newHash}
+
+	f, err := os.Open(manifest)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+
+	red := color.New(color.FgRed)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		sum, name, ok := splitSumLine(line)
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(line, `\`) {
+			name = unescapeSumName(name)
+		}
+		checked++
+
+		path, err := downloader.SafeJoin(dir, name)
+		if err != nil {
+			failed++
+			fmt.Println(red.Sprintf("[FAILED] %s: %v", name, err))
+			continue
+		}
+
+		sums, err := downloader.HashFile(path, constructors)
+		switch {
+		case os.IsNotExist(err):
+			failed++
+			fmt.Println(red.Sprintf("[MISSING] %s", name))
+		case err != nil:
+			failed++
+			fmt.Println(red.Sprintf("[FAILED] %s: %v", name, err))
+		case hex.EncodeToString(sums[algorithm]) != strings.ToLower(sum):
+			failed++
+			fmt.Println(red.Sprintf("[FAILED] %s: %s checksum mismatch", name, algorithm))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return checked, failed, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return checked, failed, nil
+}
@@ -0,0 +1,59 @@
+package blobber
+
+import (
+	"fmt"
+	"runtime"
+	rdebug "runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set at build time with
+// -ldflags "-X blobber/cmd/blobber.Version=v1.0.0 -X blobber/cmd/blobber.Commit=$(git rev-parse --short HEAD) -X blobber/cmd/blobber.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "none"
+	BuildDate = "unknown"
+)
+
+// versionCmd prints the build metadata
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version, git commit and build date",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		commit, date := buildInfo()
+		fmt.Printf("blobber %s\n", Version)
+		fmt.Printf("commit: %s\n", commit)
+		fmt.Printf("built: %s\n", date)
+		fmt.Printf("go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	},
+}
+
+func init() {
+	commit, date := buildInfo()
+	RootCmd.Version = fmt.Sprintf("%s (commit %s, built %s)", Version, commit, date)
+	RootCmd.AddCommand(versionCmd)
+}
+
+// buildInfo returns the commit and build date, falling back to the VCS stamp Go embeds
+// in plain "go build" binaries when they weren't set with -ldflags
+func buildInfo() (string, string) {
+	commit, date := Commit, BuildDate
+	info, ok := rdebug.ReadBuildInfo()
+	if !ok {
+		return commit, date
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && commit == "none":
+			commit = setting.Value
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+		case setting.Key == "vcs.time" && date == "unknown":
+			date = setting.Value
+		}
+	}
+	return commit, date
+}
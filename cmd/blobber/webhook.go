@@ -0,0 +1,154 @@
+package blobber
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"blobber/pkg/azure"
+
+	"github.com/fatih/color"
+)
+
+// webhookQueueSize bounds the findings waiting to be posted; when full, new findings are dropped
+const webhookQueueSize = 100
+
+// webhookEvent is the payload posted for each public container, and for each non-listable
+// container with blobs readable by --probe. --webhook-template receives the same fields,
+// e.g. {"text": {{json (printf "Found %s" .URL)}}}.
+type webhookEvent struct {
+	Account   string `json:"account"`
+	Container string `json:"container"`
+	Domain    string `json:"domain"`
+	URL       string `json:"url"`
+	BlobCount int    `json:"blobCount"`
+
+	SensitiveFiles []azure.SensitiveFile `json:"sensitiveFiles,omitempty"`
+
+	// Finding is "blob-accessible" for a non-listable container whose Blobs could be read
+	Finding string   `json:"finding,omitempty"`
+	Blobs   []string `json:"blobs,omitempty"`
+}
+
+// webhook posts findings from a single background goroutine so a slow endpoint never blocks scan workers
+type webhook struct {
+	url      string
+	template *template.Template
+	client   *http.Client
+	events   chan webhookEvent
+	done     chan struct{}
+	failed   int   // Owned by sender until done is closed
+	lastErr  error // Owned by sender until done is closed
+}
+
+// hook is the global webhook, nil unless --webhook-url is set
+var hook *webhook
+
+// newWebhook creates a webhook posting to url, shaping payloads with templateText if it isn't empty
+func newWebhook(url, templateText string) (*webhook, error) {
+	w := &webhook{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		events: make(chan webhookEvent, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+
+	if templateText != "" {
+		tmpl, err := template.New("webhook").Option("missingkey=error").Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}).Parse(templateText)
+		if err != nil {
+			return nil, err
+		}
+		w.template = tmpl
+	}
+
+	go w.sender()
+	return w, nil
+}
+
+// Notify queues a finding without blocking
+func (w *webhook) Notify(result azure.AccessResult, domain string) {
+	event := webhookEvent{
+		Account:        result.Account,
+		Container:      result.Container,
+		Domain:         domain,
+		URL:            fmt.Sprintf("https://%s.%s/%s", result.Account, domain, result.Container),
+		BlobCount:      result.BlobCount,
+		SensitiveFiles: result.SensitiveFiles,
+		Blobs:          result.Blobs,
+	}
+	if !result.IsPublic && len(result.Blobs) > 0 {
+		event.Finding = findingBlobAccessible
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		yellow := color.New(color.FgYellow)
+		BarPrintf(mainProgressBar, yellow, "[WARN] Webhook queue full, dropped finding %s", event.URL)
+	}
+}
+
+// Close waits for queued findings to be posted and reports failures, which may have
+// happened after the progress bar stopped rendering
+func (w *webhook) Close() {
+	close(w.events)
+	<-w.done
+
+	if w.failed > 0 {
+		red := color.New(color.FgRed)
+		fmt.Fprintln(status, red.Sprintf("%d webhook post(s) failed, last error: %v", w.failed, w.lastErr))
+	}
+}
+
+// sender posts queued findings one at a time, logging failures
+func (w *webhook) sender() {
+	defer close(w.done)
+
+	for event := range w.events {
+		if err := w.post(event); err != nil {
+			w.failed++
+			w.lastErr = err
+			red := color.New(color.FgRed)
+			BarPrintf(mainProgressBar, red, "[ERROR] Webhook for %s failed: %v", event.URL, err)
+		}
+	}
+}
+
+// post sends a single finding
+func (w *webhook) post(event webhookEvent) error {
+	var payload []byte
+	if w.template != nil {
+		var sb strings.Builder
+		if err := w.template.Execute(&sb, event); err != nil {
+			return fmt.Errorf("template error: %w", err)
+		}
+		payload = []byte(sb.String())
+	} else {
+		var err error
+		if payload, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
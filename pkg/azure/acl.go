@@ -0,0 +1,59 @@
+package azure
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Access levels reported from a Get Container ACL response
+const (
+	AccessLevelContainer     = "container"                 // Anonymous read of blobs and listing
+	AccessLevelBlob          = "blob"                      // Anonymous read of blobs only
+	AccessLevelMisconfigured = "private-but-misconfigured" // Declared private, yet listable anonymously
+	AccessLevelUnknown       = "acl-unknown"               // The ACL couldn't be read, usually because it requires auth
+)
+
+// ACLURL returns the Get Container ACL URL of a container
+func ACLURL(account, domain, container string) string {
+	return fmt.Sprintf("https://%s.%s/%s?restype=container&comp=acl", account, domain, container)
+}
+
+// ParseACL interprets a Get Container ACL response for a container that was found to be
+// publicly accessible. The level comes from the x-ms-blob-public-access header, which is
+// absent for private containers; the body lists the container's stored access policies.
+func ParseACL(resp *http.Response, body []byte) (string, []SignedIdentifier) {
+	if resp.StatusCode != http.StatusOK {
+		return AccessLevelUnknown, nil
+	}
+
+	var identifiers SignedIdentifiers
+	if len(body) > 0 {
+		xml.Unmarshal(body, &identifiers)
+	}
+
+	switch level := resp.Header.Get("x-ms-blob-public-access"); level {
+	case AccessLevelContainer, AccessLevelBlob:
+		return level, identifiers.Identifiers
+	case "":
+		return AccessLevelMisconfigured, identifiers.Identifiers
+	default:
+		return AccessLevelUnknown, identifiers.Identifiers
+	}
+}
+
+// CheckACL reads the declared public access level and stored access policies of a container
+func (s *Scanner) CheckACL(account, container string) (string, []SignedIdentifier) {
+	resp, err := s.client.Get(ACLURL(account, s.config.BaseDomain, container))
+	if err != nil {
+		return AccessLevelUnknown, nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return AccessLevelUnknown, nil
+	}
+	return ParseACL(resp, body)
+}
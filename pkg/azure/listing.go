@@ -0,0 +1,75 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+)
+
+// ListPage is one page of a container listing from either the blob or the dfs endpoint
+type ListPage struct {
+	Blobs      []Blob
+	NextMarker string
+}
+
+// ParseBlobList parses a blob endpoint restype=container&comp=list response
+func ParseBlobList(body []byte) (ListPage, error) {
+	var results EnumerationResults
+	if err := xml.Unmarshal(body, &results); err != nil {
+		return ListPage{}, err
+	}
+	return ListPage{
+		Blobs:      results.BlobList.Blobs,
+		NextMarker: results.NextMarker,
+	}, nil
+}
+
+// ParsePathList parses an ADLS Gen2 dfs endpoint resource=filesystem response.
+// continuation is the x-ms-continuation response header. Directories are skipped.
+func ParsePathList(body []byte, continuation string) (ListPage, error) {
+	var list PathList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return ListPage{}, err
+	}
+
+	page := ListPage{NextMarker: continuation}
+	for _, path := range list.Paths {
+		if path.IsDirectory {
+			continue
+		}
+		length, _ := strconv.ParseInt(path.ContentLength.String(), 10, 64)
+		page.Blobs = append(page.Blobs, Blob{
+			Name: path.Name,
+			Properties: BlobProperties{
+				LastModified:  path.LastModified,
+				Etag:          path.ETag,
+				ContentLength: length,
+			},
+		})
+	}
+	return page, nil
+}
+
+// ParseError extracts the error code from an XML (blob endpoint) or JSON (dfs endpoint) error body.
+// It returns false if the body is not an error response.
+func ParseError(body []byte) (ErrorResponse, bool) {
+	var errorResp ErrorResponse
+	if err := xml.Unmarshal(body, &errorResp); err == nil && errorResp.Code != "" {
+		return errorResp, true
+	}
+
+	var dfsError DfsErrorResponse
+	if err := json.Unmarshal(body, &dfsError); err == nil && dfsError.Error.Code != "" {
+		return ErrorResponse{Code: dfsError.Error.Code, Message: dfsError.Error.Message}, true
+	}
+	return ErrorResponse{}, false
+}
+
+// flexBool decodes booleans the dfs endpoint sends either as JSON booleans or as strings
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	*b = flexBool(string(bytes.Trim(data, `"`)) == "true")
+	return nil
+}
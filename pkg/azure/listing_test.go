@@ -0,0 +1,151 @@
+package azure
+
+import "testing"
+
+// listBlobsResponse is a List Blobs response as returned by the blob endpoint with a delimiter,
+// including the byte order mark Azure sends
+const listBlobsResponse = "\ufeff" + `<?xml version="1.0" encoding="utf-8"?>
+<EnumerationResults ServiceEndpoint="https://acme.blob.core.windows.net/" ContainerName="public">
+  <Prefix>logs/</Prefix>
+  <Marker>2!80!MDAwMDEw</Marker>
+  <MaxResults>2</MaxResults>
+  <Delimiter>/</Delimiter>
+  <Blobs>
+    <Blob>
+      <Name>logs/app.log</Name>
+      <Properties>
+        <Creation-Time>Mon, 01 Jan 2024 10:00:00 GMT</Creation-Time>
+        <Last-Modified>Tue, 02 Jan 2024 10:00:00 GMT</Last-Modified>
+        <Etag>0x8DC0B2A3E1F4C5D</Etag>
+        <Content-Length>1024</Content-Length>
+        <Content-Type>text/plain</Content-Type>
+        <Content-Encoding />
+        <Content-Language />
+        <Content-CRC64 />
+        <Content-MD5>sQqNsWTgdUEFt6mb5y4/5Q==</Content-MD5>
+        <Cache-Control />
+        <Content-Disposition />
+        <BlobType>BlockBlob</BlobType>
+        <AccessTier>Cool</AccessTier>
+        <AccessTierInferred>true</AccessTierInferred>
+        <LeaseStatus>unlocked</LeaseStatus>
+        <LeaseState>available</LeaseState>
+        <ServerEncrypted>true</ServerEncrypted>
+      </Properties>
+      <OrMetadata />
+    </Blob>
+    <BlobPrefix>
+      <Name>logs/2024/</Name>
+    </BlobPrefix>
+    <Blob>
+      <Name>logs/backup.bak</Name>
+      <Properties>
+        <Last-Modified>Wed, 03 Jan 2024 10:00:00 GMT</Last-Modified>
+        <Content-Length>0</Content-Length>
+        <BlobType>BlockBlob</BlobType>
+        <AccessTier>Archive</AccessTier>
+      </Properties>
+    </Blob>
+  </Blobs>
+  <NextMarker>2!80!MDAwMDIw</NextMarker>
+</EnumerationResults>`
+
+func TestParseBlobList(t *testing.T) {
+	page, err := ParseBlobList([]byte(listBlobsResponse))
+	if err != nil {
+		t.Fatalf("ParseBlobList: %v", err)
+	}
+
+	if page.NextMarker != "2!80!MDAwMDIw" {
+		t.Errorf("NextMarker = %q, want %q", page.NextMarker, "2!80!MDAwMDIw")
+	}
+	// The virtual directory in BlobPrefix isn't a blob
+	if len(page.Blobs) != 2 {
+		t.Fatalf("got %d blobs, want 2: %+v", len(page.Blobs), page.Blobs)
+	}
+
+	first := page.Blobs[0]
+	if first.Name != "logs/app.log" {
+		t.Errorf("Name = %q, want %q", first.Name, "logs/app.log")
+	}
+	want := BlobProperties{
+		CreationTime:    "Mon, 01 Jan 2024 10:00:00 GMT",
+		LastModified:    "Tue, 02 Jan 2024 10:00:00 GMT",
+		Etag:            "0x8DC0B2A3E1F4C5D",
+		ContentLength:   1024,
+		ContentType:     "text/plain",
+		ContentMD5:      "sQqNsWTgdUEFt6mb5y4/5Q==",
+		BlobType:        "BlockBlob",
+		AccessTier:      "Cool",
+		LeaseStatus:     "unlocked",
+		LeaseState:      "available",
+		ServerEncrypted: "true",
+	}
+	if first.Properties != want {
+		t.Errorf("Properties = %+v, want %+v", first.Properties, want)
+	}
+
+	if second := page.Blobs[1]; second.Name != "logs/backup.bak" || second.Properties.AccessTier != "Archive" {
+		t.Errorf("second blob = %+v", second)
+	}
+}
+
+func TestParseBlobListLastPage(t *testing.T) {
+	body := `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ServiceEndpoint="https://acme.blob.core.windows.net/" ContainerName="empty"><Blobs /><NextMarker /></EnumerationResults>`
+	page, err := ParseBlobList([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseBlobList: %v", err)
+	}
+	if len(page.Blobs) != 0 || page.NextMarker != "" {
+		t.Errorf("page = %+v, want no blobs and no NextMarker", page)
+	}
+}
+
+func TestParseBlobListMalformed(t *testing.T) {
+	truncated := listBlobsResponse[:len(listBlobsResponse)/2]
+	if _, err := ParseBlobList([]byte(truncated)); err == nil {
+		t.Error("ParseBlobList accepted a truncated body")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+		ok   bool
+	}{
+		{
+			name: "blob endpoint",
+			body: "\ufeff" + `<?xml version="1.0" encoding="utf-8"?><Error><Code>PublicAccessNotPermitted</Code><Message>Public access is not permitted on this storage account.
+RequestId:8e3c1f2a-601e-0044-1d2b-3c4d5e000000
+Time:2024-01-01T10:00:00.0000000Z</Message></Error>`,
+			code: "PublicAccessNotPermitted",
+			ok:   true,
+		},
+		{
+			name: "blob endpoint without XML declaration",
+			body: `<Error><Code>ContainerNotFound</Code><Message>The specified container does not exist.</Message></Error>`,
+			code: "ContainerNotFound",
+			ok:   true,
+		},
+		{
+			name: "dfs endpoint",
+			body: `{"error":{"code":"FilesystemNotFound","message":"The specified filesystem does not exist.\nRequestId:1"}}`,
+			code: "FilesystemNotFound",
+			ok:   true,
+		},
+		{name: "error without code", body: `<Error><Message>x</Message></Error>`},
+		{name: "listing", body: listBlobsResponse},
+		{name: "HTML", body: `<html><body>Service Unavailable</body></html>`},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, ok := ParseError([]byte(tt.body))
+			if ok != tt.ok || resp.Code != tt.code {
+				t.Errorf("ParseError() = (%q, %v), want (%q, %v)", resp.Code, ok, tt.code, tt.ok)
+			}
+		})
+	}
+}
@@ -1,47 +1,194 @@
 package azure
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
+	"blobber/pkg/utils"
+
 	"github.com/fatih/color"
 )
 
+// ProgressFunc receives the number of checked and total account/container combinations
+type ProgressFunc func(done, total int)
+
+// LogFunc prints a debug message. format ends with a newline.
+type LogFunc func(format string, a ...interface{})
+
+// RequestFunc sends a GET request for a check. It must honour ctx.
+type RequestFunc func(ctx context.Context, url string) (*http.Response, error)
+
 // Scanner scans Azure Blob Storage (simplified)
 type Scanner struct {
-	client *http.Client
-	config Config
+	client   *http.Client
+	config   Config
+	progress ProgressFunc
+	logf     LogFunc
+	request  RequestFunc
 }
 
-// NewScanner creates a new Scanner object (simplified)
+// NewScanner creates a new Scanner object (simplified) using the system certificate pool.
+// Use NewScannerTLS for a private CA or client certificates.
 func NewScanner(config Config) *Scanner {
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SkipSSL},
+	return newScanner(config, &tls.Config{InsecureSkipVerify: config.SkipSSL})
+}
+
+// NewScannerTLS creates a new Scanner that also trusts files.CACert and authenticates with
+// files.ClientCert and ClientKey. It fails if they can't be loaded.
+func NewScannerTLS(config Config, files TLSFiles) (*Scanner, error) {
+	tlsConfig, err := utils.NewTLSConfig(utils.TLSOptions{
+		SkipVerify:     config.SkipSSL,
+		CACertFile:     files.CACert,
+		ClientCertFile: files.ClientCert,
+		ClientKeyFile:  files.ClientKey,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return newScanner(config, tlsConfig), nil
+}
+
+// newScanner creates a Scanner with its own HTTP client using tlsConfig
+func newScanner(config Config, tlsConfig *tls.Config) *Scanner {
+	tr := utils.NewTransport(tlsConfig, utils.TransportOptions{
+		DialTimeout:           config.DialTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+	})
 
 	client := &http.Client{
 		Transport: tr,
 		Timeout:   time.Second * 30,
 	}
 
-	return &Scanner{
+	return NewScannerWithClient(config, client)
+}
+
+// NewScannerWithClient creates a Scanner that sends requests through an existing HTTP client
+func NewScannerWithClient(config Config, client *http.Client) *Scanner {
+	s := &Scanner{
 		client: client,
 		config: config,
+		logf: func(format string, a ...interface{}) {
+			fmt.Printf(format, a...)
+		},
 	}
+	s.request = s.get
+	return s
+}
+
+// SetLogger sets where debug messages go, stdout by default
+func (s *Scanner) SetLogger(fn LogFunc) {
+	s.logf = fn
+}
+
+// SetRequestFunc replaces how check requests are sent, e.g. to add rate limiting or
+// per-request timeouts. By default they go through the scanner's HTTP client.
+func (s *Scanner) SetRequestFunc(fn RequestFunc) {
+	s.request = fn
+}
+
+// get sends a GET request through the scanner's HTTP client
+func (s *Scanner) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.Do(req)
+}
+
+// SetProgress sets a callback invoked by Scan after each combination is checked.
+// Calls are serialized, so the callback doesn't need its own locking.
+func (s *Scanner) SetProgress(fn ProgressFunc) {
+	s.progress = fn
+}
+
+// Scan checks every account × container combination, running up to Config.MaxGoroutines
+// checks at once. Results are returned in account-major order. Cancelling ctx stops
+// scheduling new checks; combinations that weren't checked are left out of the results.
+func (s *Scanner) Scan(ctx context.Context, accounts, containers []string) []AccessResult {
+	workers := s.config.MaxGoroutines
+	if workers < 1 {
+		workers = 1
+	}
+
+	total := len(accounts) * len(containers)
+	results := make([]AccessResult, total)
+	checked := make([]bool, total)
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done int
+	)
+	sem := make(chan struct{}, workers)
+
+submit:
+	for i, account := range accounts {
+		for j, container := range containers {
+			select {
+			case <-ctx.Done():
+				break submit
+			case sem <- struct{}{}:
+			}
+
+			wg.Add(1)
+			go func(index int, account, container string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				result := s.CheckAccessContext(ctx, account, container)
+
+				mu.Lock()
+				defer mu.Unlock()
+				results[index] = result
+				checked[index] = true
+				done++
+				if s.progress != nil {
+					s.progress(done, total)
+				}
+			}(i*len(containers)+j, account, container)
+		}
+	}
+	wg.Wait()
+
+	scanned := results[:0]
+	for i, result := range results {
+		if checked[i] {
+			scanned = append(scanned, result)
+		}
+	}
+	return scanned
 }
 
 // CheckAccess checks access to an account and container
 func (s *Scanner) CheckAccess(account, container string) AccessResult {
-	return s.checkAccess(account, container)
+	return s.checkAccess(context.Background(), account, container)
+}
+
+// CheckAccessContext is like CheckAccess, giving up when ctx is done
+func (s *Scanner) CheckAccessContext(ctx context.Context, account, container string) AccessResult {
+	return s.checkAccess(ctx, account, container)
+}
+
+// accessURL builds the URL requested to check a container. ADLS Gen2 filesystems are
+// checked with a one-path listing, since the dfs endpoint has no restype=container request.
+func (s *Scanner) accessURL(account, container string) string {
+	if s.config.ADLS {
+		return fmt.Sprintf("https://%s.%s/%s?resource=filesystem&recursive=false&maxResults=1", account, s.config.BaseDomain, container)
+	}
+	return fmt.Sprintf("https://%s.%s/%s?restype=container", account, s.config.BaseDomain, container)
 }
 
 // checkAccess checks accessibility for a specific account and container (simplified)
-func (s *Scanner) checkAccess(account, container string) AccessResult {
-	url := fmt.Sprintf("https://%s.%s/%s?restype=container", account, s.config.BaseDomain, container)
+func (s *Scanner) checkAccess(ctx context.Context, account, container string) AccessResult {
+	url := s.accessURL(account, container)
 
 	result := AccessResult{
 		Account:   account,
@@ -50,13 +197,13 @@ func (s *Scanner) checkAccess(account, container string) AccessResult {
 	}
 
 	if s.config.Debug {
-		fmt.Printf(color.CyanString("[DEBUG] Sending request [%s/%s]: %s\n"), account, container, url)
+		s.logf(color.CyanString("[DEBUG] Sending request [%s/%s]: %s\n"), account, container, url)
 	}
 
-	resp, err := s.client.Get(url)
+	resp, err := s.request(ctx, url)
 	if err != nil {
 		if s.config.Debug {
-			fmt.Printf(color.RedString("[DEBUG] Error [%s/%s]: %v\n"), account, container, err)
+			s.logf(color.RedString("[DEBUG] Error [%s/%s]: %v\n"), account, container, err)
 		}
 		result.ErrorCode = "RequestFailed"
 		return result
@@ -64,13 +211,13 @@ func (s *Scanner) checkAccess(account, container string) AccessResult {
 	defer resp.Body.Close()
 
 	if s.config.Debug {
-		fmt.Printf(color.CyanString("[DEBUG] Response received [%s/%s]: HTTP %d\n"), account, container, resp.StatusCode)
+		s.logf(color.CyanString("[DEBUG] Response received [%s/%s]: HTTP %d\n"), account, container, resp.StatusCode)
 	}
 
 	// Successful response (HTTP 200) is directly accepted as public access
 	if resp.StatusCode == http.StatusOK {
 		if s.config.Debug {
-			fmt.Printf(color.GreenString("[DEBUG] HTTP 200 received [%s/%s], public access available\n"), account, container)
+			s.logf(color.GreenString("[DEBUG] HTTP 200 received [%s/%s], public access available\n"), account, container)
 		}
 		result.IsPublic = true
 		return result
@@ -79,43 +226,38 @@ func (s *Scanner) checkAccess(account, container string) AccessResult {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		if s.config.Debug {
-			fmt.Printf(color.RedString("[DEBUG] Error reading body [%s/%s]: %v\n"), account, container, err)
+			s.logf(color.RedString("[DEBUG] Error reading body [%s/%s]: %v\n"), account, container, err)
 		}
 		result.ErrorCode = "ReadFailed"
 		return result
 	}
 
-	// Analyze XML response
-	var errorResp ErrorResponse
-	err = xml.Unmarshal(body, &errorResp)
-	if err != nil {
-		// If XML can't be parsed or response is empty, it might be publicly accessible
+	// Analyze the XML (blob endpoint) or JSON (dfs endpoint) error response
+	errorResp, ok := ParseError(body)
+	if !ok {
+		// Without an error code there's nothing saying the container is public either,
+		// e.g. a 503 page from a proxy
 		if s.config.Debug {
-			fmt.Printf(color.GreenString("[DEBUG] XML couldn't be parsed [%s/%s], might be publicly accessible: %v\n"), account, container, err)
+			s.logf(color.YellowString("[DEBUG] No error code in HTTP %d response [%s/%s]\n"), resp.StatusCode, account, container)
 			if len(body) > 0 {
-				fmt.Printf(color.GreenString("[DEBUG] Body [%s/%s]: %s\n"), account, container, string(body))
+				s.logf(color.YellowString("[DEBUG] Body [%s/%s]: %s\n"), account, container, string(body))
 			} else {
-				fmt.Printf(color.GreenString("[DEBUG] Body [%s/%s]: <empty>\n"), account, container)
+				s.logf(color.YellowString("[DEBUG] Body [%s/%s]: <empty>\n"), account, container)
 			}
 		}
-		result.IsPublic = true
+		if resp.StatusCode >= http.StatusInternalServerError {
+			result.ErrorCode = "ServerError"
+		} else {
+			result.ErrorCode = "UnexpectedResponse"
+		}
 		return result
 	}
 
 	if s.config.Debug {
-		fmt.Printf(color.CyanString("[DEBUG] XML error code [%s/%s]: %s\n"), account, container, errorResp.Code)
-	}
-
-	if errorResp.Code == "" {
-		// ResourceNotFound error or empty error code might also indicate public access
-		if s.config.Debug {
-			fmt.Printf(color.GreenString("[DEBUG] ResourceNotFound/Empty code received [%s/%s], public access available\n"), account, container)
-		}
-		result.IsPublic = true
-	} else {
-		result.ErrorCode = errorResp.Code
+		s.logf(color.CyanString("[DEBUG] Error code [%s/%s]: %s\n"), account, container, errorResp.Code)
 	}
 
+	result.ErrorCode = errorResp.Code
 	return result
 }
 
@@ -127,7 +269,7 @@ func (s *Scanner) ListBlobs(account, container string) []string {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		if s.config.Debug {
-			fmt.Printf("[DEBUG] Error creating request: %v\n", err)
+			s.logf("[DEBUG] Error creating request: %v\n", err)
 		}
 		return []string{}
 	}
@@ -135,7 +277,7 @@ func (s *Scanner) ListBlobs(account, container string) []string {
 	resp, err := s.client.Do(req)
 	if err != nil {
 		if s.config.Debug {
-			fmt.Printf("[DEBUG] Error getting blob list: %v\n", err)
+			s.logf("[DEBUG] Error getting blob list: %v\n", err)
 		}
 		return []string{}
 	}
@@ -143,7 +285,7 @@ func (s *Scanner) ListBlobs(account, container string) []string {
 
 	if resp.StatusCode != http.StatusOK {
 		if s.config.Debug {
-			fmt.Printf("[DEBUG] Error response code: %d\n", resp.StatusCode)
+			s.logf("[DEBUG] Error response code: %d\n", resp.StatusCode)
 		}
 		return []string{}
 	}
@@ -152,7 +294,7 @@ func (s *Scanner) ListBlobs(account, container string) []string {
 	xmlData, err := io.ReadAll(resp.Body)
 	if err != nil {
 		if s.config.Debug {
-			fmt.Printf("[DEBUG] Error reading response body: %v\n", err)
+			s.logf("[DEBUG] Error reading response body: %v\n", err)
 		}
 		return []string{}
 	}
@@ -160,7 +302,7 @@ func (s *Scanner) ListBlobs(account, container string) []string {
 	var results EnumerationResults
 	if err := xml.Unmarshal(xmlData, &results); err != nil {
 		if s.config.Debug {
-			fmt.Printf("[DEBUG] Error parsing XML: %v\n", err)
+			s.logf("[DEBUG] Error parsing XML: %v\n", err)
 		}
 		return []string{}
 	}
@@ -0,0 +1,88 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// respond returns a RequestFunc answering every request with status and body
+func respond(status int, body string) RequestFunc {
+	return func(ctx context.Context, url string) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(status)
+		io.WriteString(rec, body)
+		return rec.Result(), nil
+	}
+}
+
+func TestCheckAccessClassification(t *testing.T) {
+	tests := []struct {
+		name    string
+		request RequestFunc
+		public  bool
+		code    string
+	}{
+		{"public", respond(http.StatusOK, ""), true, ""},
+		{"blob error", respond(http.StatusConflict, `<?xml version="1.0" encoding="utf-8"?><Error><Code>PublicAccessNotPermitted</Code><Message>x</Message></Error>`), false, "PublicAccessNotPermitted"},
+		{"dfs error", respond(http.StatusNotFound, `{"error":{"code":"FilesystemNotFound","message":"x"}}`), false, "FilesystemNotFound"},
+		{"server error page", respond(http.StatusServiceUnavailable, "<html>Service Unavailable</html>"), false, "ServerError"},
+		{"empty server error", respond(http.StatusBadGateway, ""), false, "ServerError"},
+		{"unexpected body", respond(http.StatusForbidden, "denied"), false, "UnexpectedResponse"},
+		{"request failed", func(ctx context.Context, url string) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		}, false, "RequestFailed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScannerWithClient(Config{BaseDomain: "blob.core.windows.net"}, http.DefaultClient)
+			s.SetRequestFunc(tt.request)
+			result := s.CheckAccess("acme", "public")
+			if result.IsPublic != tt.public || result.ErrorCode != tt.code {
+				t.Errorf("CheckAccess() = public %v, code %q, want public %v, code %q", result.IsPublic, result.ErrorCode, tt.public, tt.code)
+			}
+		})
+	}
+}
+
+func TestCheckAccessRequest(t *testing.T) {
+	var got []string
+	logged := 0
+	s := NewScannerWithClient(Config{BaseDomain: "dfs.core.windows.net", ADLS: true, Debug: true}, http.DefaultClient)
+	s.SetLogger(func(format string, a ...interface{}) { logged++ })
+	s.SetRequestFunc(func(ctx context.Context, url string) (*http.Response, error) {
+		got = append(got, url)
+		return respond(http.StatusOK, `{"paths":[]}`)(ctx, url)
+	})
+
+	s.CheckAccess("acme", "fs")
+	want := "https://acme.dfs.core.windows.net/fs?resource=filesystem&recursive=false&maxResults=1"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("requested %q, want %q", got, want)
+	}
+	if logged == 0 {
+		t.Error("debug messages didn't reach the logger")
+	}
+}
+
+func TestNewScannerTLSErrors(t *testing.T) {
+	config := Config{BaseDomain: "blob.core.windows.net"}
+	if _, err := NewScannerTLS(config, TLSFiles{}); err != nil {
+		t.Errorf("NewScannerTLS() without files: %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	for _, files := range []TLSFiles{
+		{CACert: missing},
+		{ClientCert: missing, ClientKey: missing},
+		{ClientCert: missing},
+	} {
+		if s, err := NewScannerTLS(config, files); err == nil || s != nil {
+			t.Errorf("NewScannerTLS(%+v) = %v, %v, want an error", files, s, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package azure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ScanSummary aggregates the access results of a scan
+type ScanSummary struct {
+	Checked    int            `json:"checked"`
+	Public     int            `json:"public"`
+	TotalBlobs int            `json:"totalBlobs"`
+	ErrorCodes map[string]int `json:"errorCodes,omitempty"`
+}
+
+// Summarize aggregates a set of access results
+func Summarize(results []AccessResult) ScanSummary {
+	var summary ScanSummary
+	for _, result := range results {
+		summary.Add(result)
+	}
+	return summary
+}
+
+// Add counts one more access result
+func (s *ScanSummary) Add(result AccessResult) {
+	s.Checked++
+	if result.IsPublic {
+		s.Public++
+		s.TotalBlobs += result.BlobCount
+	}
+	if result.ErrorCode != "" {
+		if s.ErrorCodes == nil {
+			s.ErrorCodes = make(map[string]int)
+		}
+		s.ErrorCodes[result.ErrorCode]++
+	}
+}
+
+// SortedErrorCodes returns the error codes seen, most frequent first
+func (s ScanSummary) SortedErrorCodes() []string {
+	codes := make([]string, 0, len(s.ErrorCodes))
+	for code := range s.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if s.ErrorCodes[codes[i]] != s.ErrorCodes[codes[j]] {
+			return s.ErrorCodes[codes[i]] > s.ErrorCodes[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// String formats the summary for human output, e.g.
+// "checked 12, public 2, 340 blobs; errors: ContainerNotFound: 9, PublicAccessNotPermitted: 1"
+func (s ScanSummary) String() string {
+	text := fmt.Sprintf("checked %d, public %d, %d blobs", s.Checked, s.Public, s.TotalBlobs)
+	if len(s.ErrorCodes) == 0 {
+		return text
+	}
+
+	codes := s.SortedErrorCodes()
+	parts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%s: %d", code, s.ErrorCodes[code]))
+	}
+	return text + "; errors: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,109 @@
+package azure
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// scanResults is a small scan with ties between error code counts
+var scanResults = []AccessResult{
+	{Account: "acme", Container: "public", IsPublic: true, BlobCount: 120},
+	{Account: "acme", Container: "www", IsPublic: true, BlobCount: 3},
+	{Account: "acme", Container: "backup", ErrorCode: "PublicAccessNotPermitted"},
+	{Account: "acme", Container: "logs", ErrorCode: "ContainerNotFound"},
+	{Account: "acme", Container: "data", ErrorCode: "ContainerNotFound"},
+	{Account: "contoso", Container: "public", ErrorCode: "AuthorizationFailure"},
+	{Account: "contoso", Container: "www", ErrorCode: "RequestFailed"},
+	{Account: "contoso", Container: "data", ErrorCode: "RequestFailed"},
+}
+
+func TestScanSummaryAdd(t *testing.T) {
+	var summary ScanSummary
+	summary.Add(AccessResult{IsPublic: true, BlobCount: 7})
+	summary.Add(AccessResult{ErrorCode: "ContainerNotFound"})
+	summary.Add(AccessResult{}) // Neither public nor failed, e.g. an empty listing
+
+	want := ScanSummary{
+		Checked:    3,
+		Public:     1,
+		TotalBlobs: 7,
+		ErrorCodes: map[string]int{"ContainerNotFound": 1},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(scanResults)
+	if summary.Checked != 8 || summary.Public != 2 || summary.TotalBlobs != 123 {
+		t.Errorf("summary = %+v, want 8 checked, 2 public, 123 blobs", summary)
+	}
+	if got := summary.ErrorCodes["ContainerNotFound"]; got != 2 {
+		t.Errorf("ContainerNotFound = %d, want 2", got)
+	}
+
+	// Blobs of containers that weren't public aren't counted
+	if got := Summarize([]AccessResult{{BlobCount: 5, ErrorCode: "ServerError"}}).TotalBlobs; got != 0 {
+		t.Errorf("TotalBlobs = %d, want 0", got)
+	}
+}
+
+func TestSortedErrorCodes(t *testing.T) {
+	// Equal counts are ordered by name
+	want := []string{"ContainerNotFound", "RequestFailed", "AuthorizationFailure", "PublicAccessNotPermitted"}
+	if got := Summarize(scanResults).SortedErrorCodes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SortedErrorCodes() = %q, want %q", got, want)
+	}
+
+	if got := (ScanSummary{}).SortedErrorCodes(); len(got) != 0 {
+		t.Errorf("SortedErrorCodes() of an empty summary = %q", got)
+	}
+}
+
+func TestScanSummaryString(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary ScanSummary
+		want    string
+	}{
+		{
+			name:    "no errors",
+			summary: ScanSummary{Checked: 2, Public: 2, TotalBlobs: 40},
+			want:    "checked 2, public 2, 40 blobs",
+		},
+		{
+			name:    "errors",
+			summary: Summarize(scanResults),
+			want:    "checked 8, public 2, 123 blobs; errors: ContainerNotFound: 2, RequestFailed: 2, AuthorizationFailure: 1, PublicAccessNotPermitted: 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanSummaryJSON(t *testing.T) {
+	data, err := json.Marshal(ScanSummary{Checked: 3, Public: 1, TotalBlobs: 9, ErrorCodes: map[string]int{"ContainerNotFound": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"checked":3,"public":1,"totalBlobs":9,"errorCodes":{"ContainerNotFound":2}}`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+
+	// errorCodes is left out when nothing failed
+	data, err = json.Marshal(ScanSummary{Checked: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"checked":1,"public":0,"totalBlobs":0}`; string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+}
@@ -1,5 +1,11 @@
 package azure
 
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
 // Config represents the configuration for blobber
 type Config struct {
 	Accounts            string
@@ -10,27 +16,47 @@ type Config struct {
 	MaxGoroutines       int
 	MaxParallelDownload int
 	BaseDomain          string
+	ADLS                bool // BaseDomain is a dfs endpoint, containers are ADLS Gen2 filesystems
 	Debug               bool
+
+	// Connection-phase timeouts, the utils defaults when zero
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+}
+
+// TLSFiles names PEM files for verifying servers with a private CA and for mutual TLS
+type TLSFiles struct {
+	CACert     string // CA bundle added to the system pool
+	ClientCert string // Client certificate, requires ClientKey
+	ClientKey  string // Private key of ClientCert
 }
 
 // ErrorResponse represents an error response from the Azure blob storage API
 type ErrorResponse struct {
-	Code    string `xml:"Code"`
-	Message string `xml:"Message"`
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
 }
 
 // BlobProperties represents Azure blob properties
 type BlobProperties struct {
-	CreationTime    string `xml:"Creation-Time"`
-	LastModified    string `xml:"Last-Modified"`
-	ContentLength   int64  `xml:"Content-Length"`
-	ContentType     string `xml:"Content-Type"`
-	ContentMD5      string `xml:"Content-MD5"`
-	BlobType        string `xml:"BlobType"`
-	AccessTier      string `xml:"AccessTier"`
-	LeaseStatus     string `xml:"LeaseStatus"`
-	LeaseState      string `xml:"LeaseState"`
-	ServerEncrypted string `xml:"ServerEncrypted"`
+	CreationTime       string `xml:"Creation-Time"`
+	LastModified       string `xml:"Last-Modified"`
+	Etag               string `xml:"Etag"`
+	ContentLength      int64  `xml:"Content-Length"`
+	ContentType        string `xml:"Content-Type"`
+	ContentEncoding    string `xml:"Content-Encoding"`
+	ContentLanguage    string `xml:"Content-Language"`
+	ContentCRC64       string `xml:"Content-CRC64"`
+	ContentMD5         string `xml:"Content-MD5"`
+	CacheControl       string `xml:"Cache-Control"`
+	ContentDisposition string `xml:"Content-Disposition"`
+	BlobType           string `xml:"BlobType"`
+	AccessTier         string `xml:"AccessTier"`
+	LeaseStatus        string `xml:"LeaseStatus"`
+	LeaseState         string `xml:"LeaseState"`
+	ServerEncrypted    string `xml:"ServerEncrypted"`
 }
 
 // Blob represents an Azure blob object
@@ -39,25 +65,81 @@ type Blob struct {
 	Properties BlobProperties `xml:"Properties"`
 }
 
-// BlobList represents a list of blobs in a container
+// BlobList represents the <Blobs> element of a container listing
 type BlobList struct {
-	Blobs      []Blob `xml:"Blobs>Blob"`
-	NextMarker string `xml:"NextMarker"`
+	Blobs []Blob `xml:"Blob"`
 }
 
-// EnumerationResults represents blob list returned from the Azure API
+// EnumerationResults represents the blob list returned by a restype=container&comp=list request
 type EnumerationResults struct {
-	ServiceEndpoint string `xml:"ServiceEndpoint,attr"`
-	ContainerName   string `xml:"ContainerName,attr"`
-	BlobList        BlobList
+	XMLName         xml.Name `xml:"EnumerationResults"`
+	ServiceEndpoint string   `xml:"ServiceEndpoint,attr"`
+	ContainerName   string   `xml:"ContainerName,attr"`
+	Prefix          string   `xml:"Prefix"`
+	Marker          string   `xml:"Marker"`
+	MaxResults      int      `xml:"MaxResults"`
+	BlobList        BlobList `xml:"Blobs"`
+	NextMarker      string   `xml:"NextMarker"`
+}
+
+// PathList represents a path listing returned by the ADLS Gen2 dfs endpoint
+type PathList struct {
+	Paths []Path `json:"paths"`
+}
+
+// Path represents a file or directory in an ADLS Gen2 filesystem
+type Path struct {
+	Name          string      `json:"name"`
+	IsDirectory   flexBool    `json:"isDirectory"`
+	ContentLength json.Number `json:"contentLength"`
+	LastModified  string      `json:"lastModified"`
+	ETag          string      `json:"etag"`
+}
+
+// DfsErrorResponse represents an error response from the dfs endpoint
+type DfsErrorResponse struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
 }
 
 // AccessResult represents an access result for a container
 type AccessResult struct {
-	Account   string
-	Container string
-	IsPublic  bool
-	ErrorCode string
-	URL       string
-	Blobs     []string
+	Account     string   `json:"account"`
+	Container   string   `json:"container"`
+	IsPublic    bool     `json:"isPublic"`
+	ErrorCode   string   `json:"errorCode,omitempty"`
+	URL         string   `json:"url"`
+	Blobs       []string `json:"blobs,omitempty"`
+	BlobCount   int      `json:"blobCount"`             // Blobs listed in a public container, 0 when they weren't listed
+	AccessLevel string   `json:"accessLevel,omitempty"` // Declared public access level, only set when the ACL was checked
+
+	// SensitiveFiles are well-known sensitive files found readable in the container
+	SensitiveFiles []SensitiveFile `json:"sensitiveFiles,omitempty"`
+}
+
+// SensitiveFile is a well-known sensitive file that could be read anonymously
+type SensitiveFile struct {
+	URL  string `json:"url"`
+	Size int64  `json:"size"` // -1 when the server didn't send a length
+}
+
+// SignedIdentifiers is the body of a Get Container ACL response
+type SignedIdentifiers struct {
+	XMLName     xml.Name           `xml:"SignedIdentifiers"`
+	Identifiers []SignedIdentifier `xml:"SignedIdentifier"`
+}
+
+// SignedIdentifier is a stored access policy of a container
+type SignedIdentifier struct {
+	ID           string       `xml:"Id"`
+	AccessPolicy AccessPolicy `xml:"AccessPolicy"`
+}
+
+// AccessPolicy holds the validity and permissions of a stored access policy
+type AccessPolicy struct {
+	Start      string `xml:"Start"`
+	Expiry     string `xml:"Expiry"`
+	Permission string `xml:"Permission"`
 }
@@ -0,0 +1,191 @@
+package downloader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion supports single Put Blob uploads of up to maxPutBlobSize
+const azureAPIVersion = "2021-08-06"
+
+// maxPutBlobSize is the largest block blob a single Put Blob request can create
+const maxPutBlobSize = 5000 << 20
+
+// azureWriter uploads objects to an Azure Blob Storage container. Credentials come from
+// AZURE_STORAGE_SAS_TOKEN, AZURE_STORAGE_KEY (the account key) or AZURE_STORAGE_CONNECTION_STRING,
+// whose BlobEndpoint also selects another endpoint such as Azurite.
+type azureWriter struct {
+	client     *http.Client
+	account    string
+	container  string
+	prefix     string
+	base       *url.URL // Container URL, blobs are appended to its path
+	sas        string   // SAS token query, without the leading "?"
+	accountKey []byte   // Decoded account key for Shared Key signing
+}
+
+func newAzureWriter(client *http.Client, account, container, prefix string) (*azureWriter, error) {
+	w := &azureWriter{
+		client:    client,
+		account:   account,
+		container: container,
+		prefix:    prefix,
+	}
+
+	endpoint := fmt.Sprintf("https://%s.blob.core.windows.net", account)
+	sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN")
+	key := os.Getenv("AZURE_STORAGE_KEY")
+	if connection := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); connection != "" {
+		settings := parseConnectionString(connection)
+		if settings["SharedAccessSignature"] != "" {
+			sas = settings["SharedAccessSignature"]
+		}
+		if settings["AccountKey"] != "" {
+			key = settings["AccountKey"]
+		}
+		if settings["BlobEndpoint"] != "" {
+			endpoint = settings["BlobEndpoint"]
+		} else if suffix := settings["EndpointSuffix"]; suffix != "" {
+			protocol := settings["DefaultEndpointsProtocol"]
+			if protocol == "" {
+				protocol = "https"
+			}
+			endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, account, suffix)
+		}
+	}
+
+	switch {
+	case sas != "":
+		w.sas = strings.TrimPrefix(sas, "?")
+	case key != "":
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Azure account key: %w", err)
+		}
+		w.accountKey = decoded
+	default:
+		return nil, errors.New("AZURE_STORAGE_SAS_TOKEN, AZURE_STORAGE_KEY or AZURE_STORAGE_CONNECTION_STRING must be set for az:// output")
+	}
+
+	u, err := url.Parse(strings.TrimRight(endpoint, "/") + "/" + container)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Azure blob endpoint: %w", err)
+	}
+	w.base = u
+	return w, nil
+}
+
+// parseConnectionString splits an Azure Storage connection string into its settings
+func parseConnectionString(connection string) map[string]string {
+	settings := make(map[string]string)
+	for _, part := range strings.Split(connection, ";") {
+		if name, value, ok := strings.Cut(part, "="); ok {
+			settings[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	return settings
+}
+
+// Create uploads the object as a block blob with a single Put Blob request, streaming the body
+func (w *azureWriter) Create(ctx context.Context, key string, size int64) (Object, error) {
+	if err := checkUploadSize(w.Location(key), size, maxPutBlobSize, "Azure Put Blob (5000 MiB)"); err != nil {
+		return nil, err
+	}
+	req, err := w.newRequest(ctx, http.MethodPut, key)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	w.sign(req, size)
+	return startUpload(w.client, req, size, func(resp *http.Response) error {
+		return checkUpload(resp, http.StatusCreated)
+	}), nil
+}
+
+// Size returns the size of an existing blob with a Get Blob Properties request
+func (w *azureWriter) Size(ctx context.Context, key string) (int64, error) {
+	req, err := w.newRequest(ctx, http.MethodHead, key)
+	if err != nil {
+		return 0, err
+	}
+	w.sign(req, 0)
+	return headSize(w.client, req, w.Location(key))
+}
+
+// Location returns the az:// URL of key
+func (w *azureWriter) Location(key string) string {
+	return fmt.Sprintf("az://%s/%s/%s", w.account, w.container, uploadKey(w.prefix, key))
+}
+
+// newRequest creates a request for the blob at key, authorized by the SAS token if there is one
+func (w *azureWriter) newRequest(ctx context.Context, method, key string) (*http.Request, error) {
+	rawURL := joinEscaped(w.base, uploadKey(w.prefix, key))
+	if w.sas != "" {
+		rawURL += "?" + w.sas
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	return req, nil
+}
+
+// sign adds a Shared Key Authorization header when an account key is used.
+// size is the Content-Length the request will be sent with.
+func (w *azureWriter) sign(req *http.Request, size int64) {
+	if w.accountKey == nil {
+		return
+	}
+
+	mac := hmac.New(sha256.New, w.accountKey)
+	mac.Write([]byte(w.stringToSign(req, size)))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", w.account, signature))
+}
+
+// stringToSign builds the Shared Key string to sign of a Blob service request
+// without query parameters. An empty body has an empty Content-Length line.
+func (w *azureWriter) stringToSign(req *http.Request, size int64) string {
+	contentLength := ""
+	if size > 0 {
+		contentLength = strconv.FormatInt(size, 10)
+	}
+
+	var msHeaders []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			msHeaders = append(msHeaders, lower+":"+strings.TrimSpace(req.Header.Get(name)))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	return strings.Join([]string{
+		req.Method,
+		"", // Content-Encoding
+		"", // Content-Language
+		contentLength,
+		"", // Content-MD5
+		req.Header.Get("Content-Type"),
+		"", // Date, sent as x-ms-date instead
+		"", // If-Modified-Since
+		"", // If-Match
+		"", // If-None-Match
+		"", // If-Unmodified-Since
+		"", // Range
+		strings.Join(msHeaders, "\n"),
+		"/" + w.account + req.URL.EscapedPath(),
+	}, "\n")
+}
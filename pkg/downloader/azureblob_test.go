@@ -0,0 +1,115 @@
+package downloader
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// devStoreKey is the published account key of the Azurite development storage account
+const devStoreKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+// devStoreWriter creates an azureWriter signing with the development storage account key
+func devStoreWriter(t *testing.T) *azureWriter {
+	t.Helper()
+	key, err := base64.StdEncoding.DecodeString(devStoreKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &azureWriter{
+		account:    "devstoreaccount1",
+		container:  "mycontainer",
+		base:       &url.URL{Scheme: "https", Host: "devstoreaccount1.blob.core.windows.net", Path: "/mycontainer"},
+		accountKey: key,
+	}
+}
+
+// sharedKeyRequest creates a blob request with fixed x-ms headers
+func sharedKeyRequest(t *testing.T, w *azureWriter, method string, headers map[string]string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, joinEscaped(w.base, "dir/my file.txt"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-ms-date", "Mon, 01 Jan 2024 00:00:00 GMT")
+	req.Header.Set("x-ms-version", azureAPIVersion)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+func TestAzureSharedKey(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		headers       map[string]string
+		size          int64
+		stringToSign  string
+		authorization string
+	}{
+		{
+			name:    "Put Blob",
+			method:  http.MethodPut,
+			headers: map[string]string{"x-ms-blob-type": "BlockBlob"},
+			size:    11,
+			stringToSign: "PUT\n\n\n11\n\n\n\n\n\n\n\n\n" +
+				"x-ms-blob-type:BlockBlob\nx-ms-date:Mon, 01 Jan 2024 00:00:00 GMT\nx-ms-version:2021-08-06\n" +
+				"/devstoreaccount1/mycontainer/dir/my%20file.txt",
+			authorization: "SharedKey devstoreaccount1:Je3Y14FPCXqdaQg0O1KPt2hLZqj/YRogH4lJ9NFPmlA=",
+		},
+		{
+			// Requests without a body leave Content-Length empty
+			name:   "Get Blob Properties",
+			method: http.MethodHead,
+			stringToSign: "HEAD\n\n\n\n\n\n\n\n\n\n\n\n" +
+				"x-ms-date:Mon, 01 Jan 2024 00:00:00 GMT\nx-ms-version:2021-08-06\n" +
+				"/devstoreaccount1/mycontainer/dir/my%20file.txt",
+			authorization: "SharedKey devstoreaccount1:8OJ0TjFhFxLLUWSyg3HdHhc1RhFK2vBbl3G6VTYsXoA=",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := devStoreWriter(t)
+			req := sharedKeyRequest(t, w, tt.method, tt.headers)
+
+			if got := w.stringToSign(req, tt.size); got != tt.stringToSign {
+				t.Errorf("stringToSign =\n%q\nwant\n%q", got, tt.stringToSign)
+			}
+			w.sign(req, tt.size)
+			if got := req.Header.Get("Authorization"); got != tt.authorization {
+				t.Errorf("Authorization = %q, want %q", got, tt.authorization)
+			}
+		})
+	}
+}
+
+func TestAzureSASIsNotSigned(t *testing.T) {
+	w := devStoreWriter(t)
+	w.accountKey = nil
+	w.sas = "sv=2021-08-06&sig=abc"
+
+	req := sharedKeyRequest(t, w, http.MethodHead, nil)
+	w.sign(req, 0)
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none with a SAS token", got)
+	}
+}
+
+func TestParseConnectionString(t *testing.T) {
+	settings := parseConnectionString("DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+		"AccountKey=" + devStoreKey + ";BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;")
+
+	want := map[string]string{
+		"DefaultEndpointsProtocol": "http",
+		"AccountName":              "devstoreaccount1",
+		"AccountKey":               devStoreKey, // Keeps the base64 padding
+		"BlobEndpoint":             "http://127.0.0.1:10000/devstoreaccount1",
+	}
+	for name, value := range want {
+		if settings[name] != value {
+			t.Errorf("%s = %q, want %q", name, settings[name], value)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"blobber/pkg/azure"
+)
+
+// Options configures a container download
+type Options struct {
+	Account      string
+	Container    string
+	BaseDomain   string
+	OutputDir    string // Blobs are saved under OutputDir, unless Writer is set
+	Parallelism  int    // Maximum number of parallel downloads (default: 1), ignored when Semaphore is set
+	SkipExisting bool   // Skip blobs whose file already exists with the expected size
+
+	// SampleBytes, if set, downloads only the first SampleBytes bytes of each blob with a Range
+	// request, saved with a SampleSuffix. Servers that ignore Range are cut off at SampleBytes.
+	SampleBytes int64
+
+	// IfModifiedSince, if set, is sent with each request; blobs the server reports
+	// as unchanged (HTTP 304) are skipped
+	IfModifiedSince time.Time
+
+	// Semaphore, if set, bounds parallel downloads instead of Parallelism.
+	// Sharing one semaphore across calls caps the total number of in-flight downloads.
+	Semaphore chan struct{}
+
+	// Writer, if set, stores blobs instead of files under OutputDir, e.g. in a bucket
+	Writer ObjectWriter
+
+	// Path, if set, returns the destination of a blob relative to OutputDir.
+	// The default is the blob name. Results escaping OutputDir are rejected.
+	Path func(azure.Blob) (string, error)
+
+	// Hashes, if set, maps algorithm names to hash constructors. Each file is hashed inline
+	// while it is downloaded; skipped existing files are read back, which needs an Opener.
+	Hashes map[string]func() hash.Hash
+
+	// BytesWriter, if set, receives a copy of all downloaded data, e.g. a byte progress bar
+	BytesWriter io.Writer
+
+	// Progress, if set, is called once per blob after it is processed.
+	// Calls are serialized, so the callback doesn't need its own locking.
+	Progress func(FileResult)
+}
+
+// SampleSuffix is appended to the file names of sampled blobs
+const SampleSuffix = ".sample"
+
+// ExpectedBytes returns how many bytes downloading a blob writes with the given
+// Options.SampleBytes, e.g. for sizing a progress bar
+func ExpectedBytes(blob azure.Blob, sampleBytes int64) int64 {
+	if sampleBytes > 0 && blob.Properties.ContentLength > sampleBytes {
+		return sampleBytes
+	}
+	return blob.Properties.ContentLength
+}
+
+// FileResult describes the outcome of a single blob download
+type FileResult struct {
+	Blob    azure.Blob
+	URL     string
+	Path    string // Destination, as described by ObjectWriter.Location
+	Bytes   int64
+	Sums    map[string][]byte // Digest per Options.Hashes algorithm
+	Skipped bool
+	Err     error
+}
+
+// Result summarizes a container download
+type Result struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+	Errors    []error
+}
+
+// DownloadContainer downloads the given blobs of a container in parallel.
+// Cancelling ctx stops scheduling new downloads and aborts the ones in flight.
+func DownloadContainer(ctx context.Context, client *http.Client, blobs []azure.Blob, opts Options) Result {
+	parallelism := opts.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	var (
+		result Result
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+	sem := opts.Semaphore
+	if sem == nil {
+		sem = make(chan struct{}, parallelism)
+	}
+	if opts.Writer == nil {
+		opts.Writer = NewLocalWriter(opts.OutputDir)
+	}
+
+	record := func(fr FileResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case fr.Err != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, fr.Err)
+		case fr.Skipped:
+			result.Skipped++
+		default:
+			result.Succeeded++
+		}
+		if opts.Progress != nil {
+			opts.Progress(fr)
+		}
+	}
+
+	for _, blob := range blobs {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return result
+		case sem <- struct{}{}: // Acquire semaphore
+		}
+
+		wg.Add(1)
+		go func(blob azure.Blob) {
+			defer wg.Done()
+			defer func() { <-sem }() // Release semaphore
+
+			record(downloadBlob(ctx, client, blob, opts))
+		}(blob)
+	}
+
+	wg.Wait()
+	return result
+}
+
+// downloadBlob downloads a single blob to the Writer
+func downloadBlob(ctx context.Context, client *http.Client, blob azure.Blob, opts Options) FileResult {
+	fr := FileResult{
+		Blob: blob,
+		URL:  fmt.Sprintf("https://%s.%s/%s/%s", opts.Account, opts.BaseDomain, opts.Container, blob.Name),
+	}
+
+	rel := blob.Name
+	if opts.Path != nil {
+		var err error
+		if rel, err = opts.Path(blob); err != nil {
+			fr.Err = fmt.Errorf("failed to build path for %s: %w", blob.Name, err)
+			return fr
+		}
+	}
+	if opts.SampleBytes > 0 {
+		rel += SampleSuffix
+	}
+	key, err := safeKey(rel)
+	if err != nil {
+		fr.Err = err
+		return fr
+	}
+	fr.Path = opts.Writer.Location(key)
+
+	if opts.SkipExisting {
+		if size, err := opts.Writer.Size(ctx, key); err == nil && size == ExpectedBytes(blob, opts.SampleBytes) {
+			fr.Skipped = true
+			if len(opts.Hashes) > 0 {
+				if fr.Sums, err = hashObject(opts.Writer, key, opts.Hashes); err != nil {
+					fr.Err = fmt.Errorf("failed to hash existing file %s: %w", fr.Path, err)
+				}
+			}
+			return fr
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fr.URL, nil)
+	if err != nil {
+		fr.Err = fmt.Errorf("failed to create request for %s: %w", fr.URL, err)
+		return fr
+	}
+	if !opts.IfModifiedSince.IsZero() {
+		req.Header.Set("If-Modified-Since", opts.IfModifiedSince.UTC().Format(http.TimeFormat))
+	}
+	if opts.SampleBytes > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", opts.SampleBytes-1))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		fr.Err = fmt.Errorf("HTTP request failed for %s: %w", fr.URL, err)
+		return fr
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && !opts.IfModifiedSince.IsZero() {
+		fr.Skipped = true
+		return fr
+	}
+	partial := opts.SampleBytes > 0 && resp.StatusCode == http.StatusPartialContent
+	if resp.StatusCode != http.StatusOK && !partial {
+		fr.Err = fmt.Errorf("download failed for %s, HTTP code: %d", fr.URL, resp.StatusCode)
+		return fr
+	}
+
+	// Servers ignoring Range are cut off at the sample size
+	size := resp.ContentLength
+	if opts.SampleBytes > 0 && size > opts.SampleBytes {
+		size = opts.SampleBytes
+	}
+	out, err := opts.Writer.Create(ctx, key, size)
+	if err != nil {
+		fr.Err = err
+		return fr
+	}
+
+	// Hash inline while reading so the file is never read twice
+	var body io.Reader = resp.Body
+	hashes, hashWriter := newHashes(opts.Hashes)
+	if hashWriter != nil {
+		body = io.TeeReader(resp.Body, hashWriter)
+	}
+
+	writers := []io.Writer{out}
+	if opts.BytesWriter != nil {
+		writers = append(writers, opts.BytesWriter)
+	}
+
+	if opts.SampleBytes > 0 {
+		fr.Bytes, err = io.CopyN(io.MultiWriter(writers...), body, opts.SampleBytes)
+		if err == io.EOF {
+			err = nil // Blob is shorter than the sample
+		}
+	} else {
+		fr.Bytes, err = io.Copy(io.MultiWriter(writers...), body)
+	}
+	if err != nil {
+		out.Abort()
+		fr.Err = fmt.Errorf("file writing error for %s: %w", fr.Path, err)
+		return fr
+	}
+	if err := out.Close(); err != nil {
+		fr.Err = fmt.Errorf("failed to save %s: %w", fr.Path, err)
+		return fr
+	}
+
+	fr.Sums = sums(hashes)
+	return fr
+}
+
+// hashObject hashes an existing object read back from w
+func hashObject(w ObjectWriter, key string, constructors map[string]func() hash.Hash) (map[string][]byte, error) {
+	opener, ok := w.(Opener)
+	if !ok {
+		return nil, errors.New("objects can't be read back from this destination")
+	}
+	r, err := opener.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return hashReader(r, constructors)
+}
+
+// newHashes instantiates the given hashes and returns them with a writer feeding all of them.
+// The writer is nil if there are no hashes.
+func newHashes(constructors map[string]func() hash.Hash) (map[string]hash.Hash, io.Writer) {
+	if len(constructors) == 0 {
+		return nil, nil
+	}
+	hashes := make(map[string]hash.Hash, len(constructors))
+	writers := make([]io.Writer, 0, len(constructors))
+	for name, newHash := range constructors {
+		h := newHash()
+		hashes[name] = h
+		writers = append(writers, h)
+	}
+	return hashes, io.MultiWriter(writers...)
+}
+
+// sums returns the digest of each hash
+func sums(hashes map[string]hash.Hash) map[string][]byte {
+	if len(hashes) == 0 {
+		return nil
+	}
+	result := make(map[string][]byte, len(hashes))
+	for name, h := range hashes {
+		result[name] = h.Sum(nil)
+	}
+	return result
+}
+
+// HashFile hashes an existing file with each of the given hash constructors
+func HashFile(path string, constructors map[string]func() hash.Hash) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return hashReader(f, constructors)
+}
+
+// hashReader hashes everything read from r with each of the given hash constructors
+func hashReader(r io.Reader, constructors map[string]func() hash.Hash) (map[string][]byte, error) {
+	hashes, w := newHashes(constructors)
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+	return sums(hashes), nil
+}
+
+// SafeJoin joins base with an untrusted relative path such as a blob name,
+// rejecting paths that would escape base (e.g. "../../etc/passwd")
+func SafeJoin(base, rel string) (string, error) {
+	key, err := safeKey(rel)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, filepath.FromSlash(key)), nil
+}
+
+// safeKey cleans an untrusted relative path into a slash-separated object key,
+// rejecting paths that would escape the destination
+func safeKey(rel string) (string, error) {
+	rel = strings.TrimLeft(filepath.FromSlash(rel), string(filepath.Separator))
+	if rel == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("unsafe path %q", rel)
+	}
+	return filepath.ToSlash(filepath.Clean(rel)), nil
+}
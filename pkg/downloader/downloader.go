@@ -1,6 +1,7 @@
 package downloader
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,39 +10,84 @@ import (
 	"strings"
 )
 
+// FileOptions configures DownloadFileContext
+type FileOptions struct {
+	BaseDomain string // Used to name the account and container in debug output
+	Debug      bool   // Print each step of the download
+}
+
 // DownloadFile downloads a file from the specified URL and saves it to the destination path
 func DownloadFile(client *http.Client, url, destPath string, baseDomain string) error {
+	return DownloadFileContext(context.Background(), client, url, destPath, FileOptions{BaseDomain: baseDomain})
+}
+
+// DownloadFileContext downloads a file from the specified URL and saves it to the destination path.
+// Cancelling ctx aborts the request or the copy in progress; a partially written file is removed.
+func DownloadFileContext(ctx context.Context, client *http.Client, url, destPath string, opts FileOptions) error {
+	// debugf prints a step as "[DEBUG] Step [account/container]: detail"
+	debugf := func(step, format string, a ...interface{}) {}
+	if opts.Debug {
+		account, container := extractAccountAndContainer(url, opts.BaseDomain)
+		debugf = func(step, format string, a ...interface{}) {
+			fmt.Printf("[DEBUG] %s [%s/%s]: %s\n", step, account, container, fmt.Sprintf(format, a...))
+		}
+	}
+
+	debugf("Starting download", "%s -> %s", url, destPath)
+
 	// Check if the destination directory exists and create if necessary
 	dir := filepath.Dir(destPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
+		debugf("Directory creation error", "%v", err)
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	// Create the file
-	out, err := os.Create(destPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-	defer out.Close()
+
+	debugf("Sending HTTP GET request", "%s", url)
 
 	// Send HTTP request
-	resp, err := client.Get(url)
+	resp, err := client.Do(req)
 	if err != nil {
+		debugf("HTTP request error", "%v", err)
 		return fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	debugf("HTTP response received", "%s", resp.Status)
+
 	// Check for failed response
 	if resp.StatusCode != http.StatusOK {
+		debugf("Failed HTTP response", "%d", resp.StatusCode)
 		return fmt.Errorf("download failed, HTTP code: %d", resp.StatusCode)
 	}
 
-	// Write file to disk
-	_, err = io.Copy(out, resp.Body)
+	debugf("Content length", "%d bytes", resp.ContentLength)
+	debugf("Content type", "%s", resp.Header.Get("Content-Type"))
+
+	// Create the file
+	out, err := os.Create(destPath)
 	if err != nil {
+		debugf("File creation error", "%v", err)
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	// Write file to disk, the copy fails once ctx is cancelled
+	bytesWritten, err := io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(destPath)
+		debugf("File writing error", "%v", err)
 		return fmt.Errorf("file writing error: %w", err)
 	}
 
+	debugf("Download completed", "%d bytes written", bytesWritten)
+
 	return nil
 }
 
@@ -72,54 +118,5 @@ func extractAccountAndContainer(url string, baseDomain string) (string, string)
 
 // DebugDownloadFile performs the download operation with debug output
 func DebugDownloadFile(client *http.Client, url, destPath string, baseDomain string) error {
-	account, container := extractAccountAndContainer(url, baseDomain)
-
-	fmt.Printf("[DEBUG] Starting download [%s/%s]: %s -> %s\n", account, container, url, destPath)
-
-	// Check if the destination directory exists and create if necessary
-	dir := filepath.Dir(destPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("[DEBUG] Directory creation error [%s/%s]: %v\n", account, container, err)
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
-
-	// Create the file
-	out, err := os.Create(destPath)
-	if err != nil {
-		fmt.Printf("[DEBUG] File creation error [%s/%s]: %v\n", account, container, err)
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
-	fmt.Printf("[DEBUG] Sending HTTP GET request [%s/%s]: %s\n", account, container, url)
-
-	// Send HTTP request
-	resp, err := client.Get(url)
-	if err != nil {
-		fmt.Printf("[DEBUG] HTTP request error [%s/%s]: %v\n", account, container, err)
-		return fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	fmt.Printf("[DEBUG] HTTP response received [%s/%s]: %d %s\n", account, container, resp.StatusCode, resp.Status)
-
-	// Check for failed response
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("[DEBUG] Failed HTTP response [%s/%s]: %d\n", account, container, resp.StatusCode)
-		return fmt.Errorf("download failed, HTTP code: %d", resp.StatusCode)
-	}
-
-	fmt.Printf("[DEBUG] Content length [%s/%s]: %d bytes\n", account, container, resp.ContentLength)
-	fmt.Printf("[DEBUG] Content type [%s/%s]: %s\n", account, container, resp.Header.Get("Content-Type"))
-
-	// Write file to disk
-	bytesWritten, err := io.Copy(out, resp.Body)
-	if err != nil {
-		fmt.Printf("[DEBUG] File writing error [%s/%s]: %v\n", account, container, err)
-		return fmt.Errorf("file writing error: %w", err)
-	}
-
-	fmt.Printf("[DEBUG] Download completed [%s/%s]. %d bytes written\n", account, container, bytesWritten)
-
-	return nil
+	return DownloadFileContext(context.Background(), client, url, destPath, FileOptions{BaseDomain: baseDomain, Debug: true})
 }
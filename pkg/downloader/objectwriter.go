@@ -0,0 +1,210 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ObjectWriter stores downloaded blobs, on local disk or in remote object storage.
+// Keys are slash-separated relative paths such as "account/container/dir/file.txt".
+type ObjectWriter interface {
+	// Create starts writing the object at key. size is the exact number of bytes that
+	// will be written, or -1 if unknown; remote writers need it up front.
+	Create(ctx context.Context, key string, size int64) (Object, error)
+
+	// Size returns the size of an existing object, or an error wrapping os.ErrNotExist
+	Size(ctx context.Context, key string) (int64, error)
+
+	// Location describes where key is stored, e.g. a file path or an s3:// URL
+	Location(key string) string
+}
+
+// Object is an object being written. Close commits it; Abort discards it instead.
+type Object interface {
+	io.Writer
+	Close() error
+	Abort()
+}
+
+// Opener is implemented by ObjectWriters whose objects can be read back, which is
+// needed to hash skipped existing objects
+type Opener interface {
+	Open(key string) (io.ReadCloser, error)
+}
+
+// NewObjectWriter creates a remote ObjectWriter for an s3://bucket/prefix or
+// az://account/container/prefix URL. Credentials are read from the environment.
+func NewObjectWriter(rawURL string, client *http.Client) (ObjectWriter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket or account in %q", rawURL)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3":
+		return newS3Writer(client, u.Host, prefix)
+	case "az":
+		container, prefix, _ := strings.Cut(prefix, "/")
+		if container == "" {
+			return nil, fmt.Errorf("missing container in %q, expected az://account/container/prefix", rawURL)
+		}
+		return newAzureWriter(client, u.Host, container, prefix)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, use s3:// or az://", u.Scheme)
+	}
+}
+
+// LocalWriter stores objects as files under a directory
+type LocalWriter struct {
+	Dir string
+}
+
+// NewLocalWriter creates an ObjectWriter saving files under dir
+func NewLocalWriter(dir string) *LocalWriter {
+	return &LocalWriter{Dir: dir}
+}
+
+// Create creates the file for key along with its parent directories. Data is written to a
+// temporary file that replaces the destination on Close, so an existing file (which may be
+// a hardlink shared with other paths) is never truncated in place.
+func (w *LocalWriter) Create(_ context.Context, key string, _ int64) (Object, error) {
+	path := w.Location(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	return &localObject{File: f, path: path}, nil
+}
+
+// Size returns the size of the file for key
+func (w *LocalWriter) Size(_ context.Context, key string) (int64, error) {
+	info, err := os.Stat(w.Location(key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Location returns the file path of key
+func (w *LocalWriter) Location(key string) string {
+	return filepath.Join(w.Dir, filepath.FromSlash(key))
+}
+
+// Open opens the file for key
+func (w *LocalWriter) Open(key string) (io.ReadCloser, error) {
+	return os.Open(w.Location(key))
+}
+
+// localObject is a temporary file being written in place of path
+type localObject struct {
+	*os.File
+	path string
+}
+
+// Close closes the temporary file and renames it over the destination
+func (o *localObject) Close() error {
+	if err := o.File.Close(); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+	if err := os.Chmod(o.Name(), 0644); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+	if err := os.Rename(o.Name(), o.path); err != nil {
+		os.Remove(o.Name())
+		return err
+	}
+	return nil
+}
+
+// Abort removes the partially written file, leaving the destination untouched
+func (o *localObject) Abort() {
+	o.File.Close()
+	os.Remove(o.Name())
+}
+
+// errAborted fails the body of an aborted upload
+var errAborted = errors.New("upload aborted")
+
+// uploadObject streams an object into an HTTP upload running in the background
+type uploadObject struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+// startUpload sends req with a body fed by the returned Object. check inspects the response.
+func startUpload(client *http.Client, req *http.Request, size int64, check func(*http.Response) error) Object {
+	pr, pw := io.Pipe()
+	req.Body = pr
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+		pr.Close()
+	}
+
+	o := &uploadObject{pw: pw, done: make(chan error, 1)}
+	go func() {
+		resp, err := client.Do(req)
+		if err != nil {
+			pr.CloseWithError(err) // Unblock writers if the request failed early
+			o.done <- err
+			return
+		}
+		defer resp.Body.Close()
+		err = check(resp)
+		pr.CloseWithError(err)
+		o.done <- err
+	}()
+	return o
+}
+
+func (o *uploadObject) Write(p []byte) (int, error) {
+	return o.pw.Write(p)
+}
+
+// Close finishes the body and waits for the upload to complete
+func (o *uploadObject) Close() error {
+	o.pw.Close()
+	return <-o.done
+}
+
+// Abort cancels the upload by failing its body
+func (o *uploadObject) Abort() {
+	o.pw.CloseWithError(errAborted)
+	<-o.done
+}
+
+// checkUploadSize rejects objects a single upload request can't create: those of unknown
+// size, which must be sent up front, and those over the service's limit
+func checkUploadSize(location string, size, limit int64, request string) error {
+	if size < 0 {
+		return fmt.Errorf("size of %s is unknown, it is required for uploads", location)
+	}
+	if size > limit {
+		return fmt.Errorf("%s is %d bytes, over the %d-byte limit of a single %s; multipart uploads aren't supported", location, size, limit, request)
+	}
+	return nil
+}
+
+// uploadKey joins a key prefix and key
+func uploadKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
@@ -0,0 +1,236 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// objectStore is a fake S3/Azure endpoint keeping uploaded objects by escaped path
+type objectStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    map[string]string // Authorization header of each upload
+	query   map[string]string // Query of each upload
+	created int               // Status of successful uploads
+}
+
+func newObjectStore(t *testing.T, created int) (*objectStore, *httptest.Server) {
+	t.Helper()
+	store := &objectStore{
+		objects: make(map[string][]byte),
+		auth:    make(map[string]string),
+		query:   make(map[string]string),
+		created: created,
+	}
+	srv := httptest.NewServer(store)
+	t.Cleanup(srv.Close)
+	return store, srv
+}
+
+func (s *objectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := r.URL.EscapedPath()
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("Authorization") == "" && r.URL.Query().Get("sig") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "AuthenticationFailed")
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(body)) != r.ContentLength {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.objects[path] = body
+		s.auth[path] = r.Header.Get("Authorization")
+		s.query[path] = r.URL.RawQuery
+		w.WriteHeader(s.created)
+	case http.MethodHead:
+		body, ok := s.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// object returns a stored object and whether it exists
+func (s *objectStore) object(path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body, ok := s.objects[path]
+	return body, ok
+}
+
+// roundTrip uploads data to key, then checks it is stored at path and reported by Size
+func roundTrip(t *testing.T, w ObjectWriter, store *objectStore, key, path, data string) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := w.Size(ctx, key); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Size before upload: err = %v, want os.ErrNotExist", err)
+	}
+
+	out, err := w.Create(ctx, key, int64(len(data)))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := io.Copy(out, strings.NewReader(data)); err != nil {
+		t.Fatalf("writing: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got, ok := store.object(path); !ok || string(got) != data {
+		t.Errorf("stored %q at %s (exists %v), want %q", got, path, ok, data)
+	}
+	size, err := w.Size(ctx, key)
+	if err != nil || size != int64(len(data)) {
+		t.Errorf("Size = %d, %v, want %d", size, err, len(data))
+	}
+}
+
+// setUploadEnv clears upload credentials from the environment and sets the given ones
+func setUploadEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION", "AWS_DEFAULT_REGION",
+		"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL",
+		"AZURE_STORAGE_SAS_TOKEN", "AZURE_STORAGE_KEY", "AZURE_STORAGE_CONNECTION_STRING",
+	} {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestS3WriterRoundTrip(t *testing.T) {
+	store, srv := newObjectStore(t, http.StatusOK)
+	setUploadEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":     "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY": "secretEXAMPLE",
+		"AWS_REGION":            "eu-west-1",
+		"AWS_ENDPOINT_URL_S3":   srv.URL,
+	})
+
+	w, err := NewObjectWriter("s3://loot/run1", srv.Client())
+	if err != nil {
+		t.Fatalf("NewObjectWriter: %v", err)
+	}
+	if got := w.Location("acme/public/a b.txt"); got != "s3://loot/run1/acme/public/a b.txt" {
+		t.Errorf("Location = %q", got)
+	}
+
+	roundTrip(t, w, store, "acme/public/a b.txt", "/loot/run1/acme/public/a%20b.txt", "hello world")
+	if auth := store.auth["/loot/run1/acme/public/a%20b.txt"]; !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature for eu-west-1", auth)
+	}
+	roundTrip(t, w, store, "acme/public/empty", "/loot/run1/acme/public/empty", "")
+}
+
+func TestAzureWriterRoundTrip(t *testing.T) {
+	store, srv := newObjectStore(t, http.StatusCreated)
+	setUploadEnv(t, map[string]string{
+		"AZURE_STORAGE_CONNECTION_STRING": "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+			"AccountKey=" + devStoreKey + ";BlobEndpoint=" + srv.URL + "/devstoreaccount1;",
+	})
+
+	w, err := NewObjectWriter("az://devstoreaccount1/loot/run1", srv.Client())
+	if err != nil {
+		t.Fatalf("NewObjectWriter: %v", err)
+	}
+
+	path := "/devstoreaccount1/loot/run1/acme/public/a%20b.txt"
+	roundTrip(t, w, store, "acme/public/a b.txt", path, "hello world")
+	if auth := store.auth[path]; !strings.HasPrefix(auth, "SharedKey devstoreaccount1:") {
+		t.Errorf("Authorization = %q, want Shared Key", auth)
+	}
+}
+
+func TestAzureWriterSASRoundTrip(t *testing.T) {
+	store, srv := newObjectStore(t, http.StatusCreated)
+	setUploadEnv(t, map[string]string{
+		"AZURE_STORAGE_SAS_TOKEN":         "?sv=2021-08-06&sp=cw&sig=abc",
+		"AZURE_STORAGE_CONNECTION_STRING": "BlobEndpoint=" + srv.URL + "/acct",
+	})
+
+	w, err := NewObjectWriter("az://acct/loot", srv.Client())
+	if err != nil {
+		t.Fatalf("NewObjectWriter: %v", err)
+	}
+
+	roundTrip(t, w, store, "a.txt", "/acct/loot/a.txt", "hello")
+	if store.auth["/acct/loot/a.txt"] != "" || store.query["/acct/loot/a.txt"] != "sv=2021-08-06&sp=cw&sig=abc" {
+		t.Errorf("upload sent Authorization %q and query %q, want the SAS token only", store.auth["/acct/loot/a.txt"], store.query["/acct/loot/a.txt"])
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+	_, srv := newObjectStore(t, http.StatusCreated)
+	setUploadEnv(t, map[string]string{
+		"AZURE_STORAGE_CONNECTION_STRING": "BlobEndpoint=" + srv.URL + "/acct;SharedAccessSignature=sv=2021",
+	})
+
+	w, err := NewObjectWriter("az://acct/loot", srv.Client())
+	if err != nil {
+		t.Fatalf("NewObjectWriter: %v", err)
+	}
+	out, err := w.Create(context.Background(), "a.txt", 5)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	out.Write([]byte("hello"))
+	if err := out.Close(); err == nil || !strings.Contains(err.Error(), "AuthenticationFailed") {
+		t.Errorf("Close error = %v, want the service's message", err)
+	}
+}
+
+func TestUploadSizeLimits(t *testing.T) {
+	setUploadEnv(t, map[string]string{
+		"AWS_ACCESS_KEY_ID":       "AKIDEXAMPLE",
+		"AWS_SECRET_ACCESS_KEY":   "secretEXAMPLE",
+		"AWS_ENDPOINT_URL_S3":     "http://127.0.0.1:1", // Nothing may be sent
+		"AZURE_STORAGE_SAS_TOKEN": "sig=abc",
+	})
+	s3, err := NewObjectWriter("s3://loot", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	az, err := NewObjectWriter("az://acct/loot", http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		w    ObjectWriter
+		size int64
+		want string
+	}{
+		{"S3 over 5 GiB", s3, maxS3PutSize + 1, "limit of a single S3 PUT"},
+		{"Azure over 5000 MiB", az, maxPutBlobSize + 1, "limit of a single Azure Put Blob"},
+		{"unknown size", s3, -1, "size of s3://loot/big.bin is unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.w.Create(context.Background(), "big.bin", tt.size)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Create() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
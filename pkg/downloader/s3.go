@@ -0,0 +1,220 @@
+package downloader
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unsignedPayload lets uploads be signed before their body is streamed
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// maxS3PutSize is the largest object a single PUT can upload
+const maxS3PutSize = 5 << 30
+
+// s3Writer uploads objects to an S3 bucket with SigV4-signed requests. Credentials come from
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, the region from AWS_REGION
+// or AWS_DEFAULT_REGION. AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL selects an S3-compatible
+// service, addressed path-style.
+type s3Writer struct {
+	client       *http.Client
+	bucket       string
+	prefix       string
+	base         *url.URL // Bucket URL, objects are appended to its path
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newS3Writer(client *http.Client, bucket, prefix string) (*s3Writer, error) {
+	w := &s3Writer{
+		client:       client,
+		bucket:       bucket,
+		prefix:       prefix,
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if w.accessKey == "" || w.secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set for s3:// output")
+	}
+	if w.region == "" {
+		w.region = "us-east-1"
+	}
+
+	base := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, w.region)
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		base = strings.TrimRight(endpoint, "/") + "/" + bucket
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	w.base = u
+	return w, nil
+}
+
+// Create uploads the object with a single PUT, streaming the body
+func (w *s3Writer) Create(ctx context.Context, key string, size int64) (Object, error) {
+	if err := checkUploadSize(w.Location(key), size, maxS3PutSize, "S3 PUT (5 GiB)"); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, w.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	w.sign(req)
+	return startUpload(w.client, req, size, func(resp *http.Response) error {
+		return checkUpload(resp, http.StatusOK)
+	}), nil
+}
+
+// Size returns the size of an existing object with a HEAD request
+func (w *s3Writer) Size(ctx context.Context, key string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, w.objectURL(key), nil)
+	if err != nil {
+		return 0, err
+	}
+	w.sign(req)
+	return headSize(w.client, req, w.Location(key))
+}
+
+// Location returns the s3:// URL of key
+func (w *s3Writer) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", w.bucket, uploadKey(w.prefix, key))
+}
+
+// objectURL returns the request URL of key
+func (w *s3Writer) objectURL(key string) string {
+	return joinEscaped(w.base, uploadKey(w.prefix, key))
+}
+
+// sign adds SigV4 authentication headers to a request with an unsigned payload
+func (w *s3Writer) sign(req *http.Request) {
+	w.signAt(req, time.Now().UTC(), unsignedPayload)
+}
+
+// signAt adds SigV4 authentication headers to a request signed at now, whose body has the
+// hex SHA-256 payloadHash (or is unsignedPayload)
+func (w *s3Writer) signAt(req *http.Request, now time.Time, payloadHash string) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if w.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", w.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + w.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+w.secretKey), date)
+	for _, part := range []string{w.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		w.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// joinEscaped appends a slash-separated key to the path of base, percent-encoding
+// everything but unreserved characters and slashes as S3 and Azure signing expect
+func joinEscaped(base *url.URL, key string) string {
+	u := *base
+	var escaped strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	u.RawPath = strings.TrimRight(base.EscapedPath(), "/") + "/" + escaped.String()
+	u.Path = strings.TrimRight(base.Path, "/") + "/" + key
+	return u.String()
+}
+
+// headSize sends a HEAD request and returns the Content-Length of the object
+func headSize(client *http.Client, req *http.Request, location string) (int64, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.ContentLength, nil
+	case http.StatusNotFound:
+		return 0, fmt.Errorf("%s: %w", location, os.ErrNotExist)
+	default:
+		return 0, fmt.Errorf("checking %s failed, HTTP code: %d", location, resp.StatusCode)
+	}
+}
+
+// checkUpload turns an unexpected upload response into an error including the service's message
+func checkUpload(resp *http.Response, want int) error {
+	if resp.StatusCode == want {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("upload failed, HTTP code: %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
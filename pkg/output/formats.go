@@ -0,0 +1,140 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func init() {
+	Register("text", NewText)
+	Register("json", NewJSON)
+	Register("jsonl", NewJSONL)
+	Register("csv", NewCSV)
+}
+
+// text writes one blob URL per line
+type text struct {
+	w *bufio.Writer
+}
+
+// NewText creates a Writer printing one blob URL per line
+func NewText(w io.Writer) Writer {
+	return &text{w: bufio.NewWriter(w)}
+}
+
+func (t *text) Write(r Record) error {
+	_, err := fmt.Fprintln(t.w, r.URL)
+	return err
+}
+
+func (t *text) Flush() error {
+	return t.w.Flush()
+}
+
+func (t *text) Close() error {
+	return t.w.Flush()
+}
+
+// jsonl writes one JSON object per line
+type jsonl struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONL creates a Writer printing one JSON object per line
+func NewJSONL(w io.Writer) Writer {
+	bw := bufio.NewWriter(w)
+	return &jsonl{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (j *jsonl) Write(r Record) error {
+	return j.enc.Encode(r)
+}
+
+func (j *jsonl) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *jsonl) Close() error {
+	return j.w.Flush()
+}
+
+// jsonArray streams records as a single JSON array
+type jsonArray struct {
+	w       *bufio.Writer
+	written bool
+}
+
+// NewJSON creates a Writer printing records as one JSON array
+func NewJSON(w io.Writer) Writer {
+	return &jsonArray{w: bufio.NewWriter(w)}
+}
+
+func (j *jsonArray) Write(r Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if !j.written {
+		sep = "[\n  "
+		j.written = true
+	}
+	j.w.WriteString(sep)
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonArray) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *jsonArray) Close() error {
+	if j.written {
+		j.w.WriteString("\n]\n")
+	} else {
+		j.w.WriteString("[]\n")
+	}
+	return j.w.Flush()
+}
+
+// csvWriter writes records as CSV with a header row
+type csvWriter struct {
+	w       *csv.Writer
+	started bool
+}
+
+// csvHeader is the first row of CSV output
+var csvHeader = []string{"account", "container", "domain", "url", "name", "size", "content_type", "last_modified", "finding"}
+
+// NewCSV creates a Writer printing records as CSV with a header row
+func NewCSV(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Write(r Record) error {
+	if !c.started {
+		c.started = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	return c.w.Write([]string{
+		r.Account, r.Container, r.Domain, r.URL, r.Name,
+		strconv.FormatInt(r.Size, 10), r.ContentType, r.LastModified, r.Finding,
+	})
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
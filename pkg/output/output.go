@@ -0,0 +1,138 @@
+// Package output writes listed blobs in pluggable formats. Formats are looked up by name
+// in a registry, so library users can add their own with Register.
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Record describes one listed blob
+type Record struct {
+	Account      string `json:"account"`
+	Container    string `json:"container"`
+	Domain       string `json:"domain"`
+	URL          string `json:"url"`
+	Name         string `json:"name"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"contentType,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	// Finding tells why a blob was reported other than by listing, e.g. "sensitive-file"
+	// for a readable well-known file. Empty for listed blobs.
+	Finding string `json:"finding,omitempty"`
+}
+
+// Writer writes records in one format. Close writes any trailer (e.g. a closing bracket)
+// but doesn't close the underlying io.Writer.
+type Writer interface {
+	Write(Record) error
+	Close() error
+}
+
+// Flusher is implemented by Writers that buffer output. Sink flushes after every Write call,
+// so records of one container reach the destination together.
+type Flusher interface {
+	Flush() error
+}
+
+// Factory creates a Writer writing to w
+type Factory func(w io.Writer) Writer
+
+var (
+	registry     = make(map[string]Factory)
+	registryLock sync.RWMutex
+)
+
+// Register makes a format available by name. It panics if the name is already registered.
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if factory == nil {
+		panic("output: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("output: Register called twice for format " + name)
+	}
+	registry[name] = factory
+}
+
+// Get returns the factory of a registered format
+func Get(name string) (Factory, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(formats(), ", "))
+	}
+	return factory, nil
+}
+
+// Formats returns the registered format names in sorted order
+func Formats() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return formats()
+}
+
+// formats lists the format names. Caller must hold registryLock.
+func formats() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sink makes a Writer safe for concurrent use and closes its destination along with it
+type Sink struct {
+	mu      sync.Mutex
+	w       Writer
+	closers []io.Closer
+	count   int
+}
+
+// NewSink wraps w. closers are closed in order after w, e.g. a gzip writer and then its file.
+func NewSink(w Writer, closers ...io.Closer) *Sink {
+	return &Sink{w: w, closers: closers}
+}
+
+// Write writes records in order without interleaving them with other callers
+func (s *Sink) Write(records ...Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		if err := s.w.Write(record); err != nil {
+			return err
+		}
+		s.count++
+	}
+	if f, ok := s.w.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Count returns the number of records written so far
+func (s *Sink) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Close finishes the format and closes the destination, returning the first error
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.w.Close()
+	for _, c := range s.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"sync"
 
 	"github.com/schollz/progressbar/v3"
@@ -9,19 +11,65 @@ import (
 
 // ProgressBar represents a progress bar for download operations
 type ProgressBar struct {
-	bar *progressbar.ProgressBar
-	mu  sync.Mutex
+	bar    *progressbar.ProgressBar
+	writer io.Writer
+	mu     sync.Mutex
+}
+
+// barConfig holds the settings applied by Options
+type barConfig struct {
+	description string
+	bytes       bool
+	byteTotal   int64
+	writer      io.Writer
+}
+
+// Option configures a ProgressBar
+type Option func(*barConfig)
+
+// WithDescription sets the text shown before the bar
+func WithDescription(description string) Option {
+	return func(c *barConfig) {
+		c.description = description
+	}
+}
+
+// WithWriter renders the bar to w instead of stdout, e.g. a writer shared with other output
+func WithWriter(w io.Writer) Option {
+	return func(c *barConfig) {
+		c.writer = w
+	}
+}
+
+// WithBytes switches the bar to byte mode with the given total number of bytes.
+// In byte mode the bar is advanced with AddBytes or by writing to it.
+func WithBytes(total int64) Option {
+	return func(c *barConfig) {
+		c.bytes = true
+		c.byteTotal = total
+	}
 }
 
 // NewProgressBar creates a new progress bar
-func NewProgressBar(total int) *ProgressBar {
-	bar := progressbar.NewOptions(
-		total,
+func NewProgressBar(total int, opts ...Option) *ProgressBar {
+	config := barConfig{
+		description: "[cyan]Downloading...[reset]",
+		byteTotal:   int64(total),
+		writer:      os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	bar := progressbar.NewOptions64(
+		config.byteTotal,
+		progressbar.OptionSetWriter(config.writer),
 		progressbar.OptionEnableColorCodes(true),
-		progressbar.OptionShowBytes(false),
+		progressbar.OptionShowBytes(config.bytes),
 		progressbar.OptionShowCount(),
 		progressbar.OptionSetWidth(40),
-		progressbar.OptionSetDescription("[cyan]Downloading...[reset]"),
+		progressbar.OptionSetDescription(config.description),
+		progressbar.OptionOnCompletion(func() { fmt.Fprintln(config.writer) }),
 		progressbar.OptionSetTheme(progressbar.Theme{
 			Saucer:        "[green]=[reset]",
 			SaucerHead:    "[green]>[reset]",
@@ -32,10 +80,16 @@ func NewProgressBar(total int) *ProgressBar {
 	)
 
 	return &ProgressBar{
-		bar: bar,
+		bar:    bar,
+		writer: config.writer,
 	}
 }
 
+// Bar returns the underlying progress bar, e.g. for printing above it with progressbar.Bprintf
+func (p *ProgressBar) Bar() *progressbar.ProgressBar {
+	return p.bar
+}
+
 // Increment increments the progress bar by one
 func (p *ProgressBar) Increment() {
 	p.mu.Lock()
@@ -43,11 +97,24 @@ func (p *ProgressBar) Increment() {
 	p.bar.Add(1)
 }
 
+// AddBytes advances a byte mode progress bar by n bytes
+func (p *ProgressBar) AddBytes(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bar.Add64(n)
+}
+
+// Write implements io.Writer so the bar can track data passed through io.Copy or io.MultiWriter
+func (p *ProgressBar) Write(b []byte) (int, error) {
+	p.AddBytes(int64(len(b)))
+	return len(b), nil
+}
+
 // Clear clears the progress bar
 func (p *ProgressBar) Clear() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	fmt.Println()
+	fmt.Fprintln(p.writer)
 	p.bar.Clear()
 }
 
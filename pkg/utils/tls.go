@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures certificate verification and client authentication
+type TLSOptions struct {
+	SkipVerify     bool
+	CACertFile     string // PEM bundle appended to the system certificate pool
+	ClientCertFile string // PEM client certificate for mutual TLS
+	ClientKeyFile  string // PEM private key for the client certificate
+}
+
+// NewTLSConfig builds a tls.Config from the given options
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: opts.SkipVerify}
+
+	if opts.CACertFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", opts.CACertFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if opts.ClientCertFile != "" || opts.ClientKeyFile != "" {
+		if opts.ClientCertFile == "" || opts.ClientKeyFile == "" {
+			return nil, errors.New("both client certificate and client key are required for mutual TLS")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
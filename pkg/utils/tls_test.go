@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePEM writes a single PEM block to a file in dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// selfSigned creates a self-signed certificate and returns it with its private key
+func selfSigned(t *testing.T, commonName string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// writeKeyPair writes a certificate and key as PEM files and returns their paths
+func writeKeyPair(t *testing.T, dir, name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name+".crt", "CERTIFICATE", cert.Raw),
+		writePEM(t, dir, name+".key", "EC PRIVATE KEY", keyDER)
+}
+
+func TestNewTLSConfigCACert(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // The unverified request logs a handshake error
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The httptest certificate is self-signed, so it is its own CA
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+
+	config, err := NewTLSConfig(TLSOptions{CACertFile: caFile})
+	if err != nil {
+		t.Fatalf("NewTLSConfig: %v", err)
+	}
+	client := &http.Client{Transport: NewTransport(config, TransportOptions{})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with CA bundle failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	// Without the bundle the same server must fail verification
+	config, err = NewTLSConfig(TLSOptions{})
+	if err != nil {
+		t.Fatalf("NewTLSConfig: %v", err)
+	}
+	client = &http.Client{Transport: NewTransport(config, TransportOptions{})}
+	if resp, err := client.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("request without CA bundle succeeded, want verification error")
+	}
+}
+
+func TestNewTLSConfigClientCert(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := selfSigned(t, "client")
+	certFile, keyFile := writeKeyPair(t, dir, "client", cert, key)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	srv.StartTLS()
+	defer srv.Close()
+
+	config, err := NewTLSConfig(TLSOptions{SkipVerify: true, ClientCertFile: certFile, ClientKeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewTLSConfig: %v", err)
+	}
+	client := &http.Client{Transport: NewTransport(config, TransportOptions{})}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := selfSigned(t, "one")
+	certFile, keyFile := writeKeyPair(t, dir, "one", cert, key)
+	_, otherKey := selfSigned(t, "two")
+	_, otherKeyFile := writeKeyPair(t, dir, "two", cert, otherKey)
+	notPEM := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts TLSOptions
+		want string
+	}{
+		{"mismatched key", TLSOptions{ClientCertFile: certFile, ClientKeyFile: otherKeyFile}, "failed to load client certificate"},
+		{"cert without key", TLSOptions{ClientCertFile: certFile}, "both client certificate and client key"},
+		{"key without cert", TLSOptions{ClientKeyFile: keyFile}, "both client certificate and client key"},
+		{"missing CA file", TLSOptions{CACertFile: filepath.Join(dir, "missing.pem")}, "failed to read CA certificate"},
+		{"CA file without certificates", TLSOptions{CACertFile: notPEM}, "no valid certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTLSConfig(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewTLSConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Default connection-phase timeouts, short so dead hosts fail fast
+const (
+	DefaultDialTimeout           = 5 * time.Second
+	DefaultTLSHandshakeTimeout   = 5 * time.Second
+	DefaultResponseHeaderTimeout = 15 * time.Second
+)
+
+// TransportOptions bounds the phases of a request before the body is read. Zero values
+// use the defaults above. None of them limit the body transfer itself.
+type TransportOptions struct {
+	DialTimeout           time.Duration // TCP connection, including the DNS lookup
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration // From the request being sent to the response headers
+}
+
+// NewTransport creates an HTTP transport with the given TLS configuration and timeouts
+func NewTransport(tlsConfig *tls.Config, opts TransportOptions) *http.Transport {
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+	if opts.TLSHandshakeTimeout <= 0 {
+		opts.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	}
+	if opts.ResponseHeaderTimeout <= 0 {
+		opts.ResponseHeaderTimeout = DefaultResponseHeaderTimeout
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   opts.DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		TLSClientConfig:       tlsConfig,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+	}
+}